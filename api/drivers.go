@@ -0,0 +1,67 @@
+// Package api is the registry of goquery API drivers. Driver packages register a factory
+// under a name from an init function, and a binary picks one by the apiDriver set in its
+// config, passing it the driver's own block from the drivers section of the config.
+// Importing a driver package, even only for its side effects, is what makes it available.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// DriverFactory creates an API from the driver's config block, which is an empty JSON
+// object if the config has none. debug is the debugEnabled setting of the goquery config.
+type DriverFactory func(settings json.RawMessage, debug bool) (models.GoQueryAPI, error)
+
+var (
+	driversMutex sync.Mutex
+	drivers      = make(map[string]DriverFactory)
+)
+
+// Register makes a driver available by name. It panics if the name is taken or factory
+// is nil, both of which are programming errors.
+func Register(name string, factory DriverFactory) {
+	driversMutex.Lock()
+	defer driversMutex.Unlock()
+	if factory == nil {
+		panic("api: Register factory is nil for driver " + name)
+	}
+	if _, ok := drivers[name]; ok {
+		panic("api: Register called twice for driver " + name)
+	}
+	drivers[name] = factory
+}
+
+// Drivers returns the names of the registered drivers, sorted
+func Drivers() []string {
+	driversMutex.Lock()
+	defer driversMutex.Unlock()
+	names := make([]string, 0, len(drivers))
+	for name := range drivers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Create creates an API with the driver registered as name
+func Create(name string, settings json.RawMessage, debug bool) (models.GoQueryAPI, error) {
+	if name == "" {
+		return nil, fmt.Errorf("No apiDriver is configured, available drivers are: %s", strings.Join(Drivers(), ", "))
+	}
+	driversMutex.Lock()
+	factory, ok := drivers[name]
+	driversMutex.Unlock()
+	if !ok {
+		return nil, fmt.Errorf("Unknown apiDriver '%s', available drivers are: %s", name, strings.Join(Drivers(), ", "))
+	}
+	if len(settings) == 0 {
+		settings = json.RawMessage("{}")
+	}
+	return factory(settings, debug)
+}
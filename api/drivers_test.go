@@ -0,0 +1,94 @@
+package api
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// testAPI is the API created by the drivers registered in these tests
+type testAPI struct {
+	settings string
+	debug    bool
+}
+
+func (api *testAPI) CheckHost(uuid string) (hosts.Host, error) {
+	return hosts.Host{}, models.ErrHostNotFound
+}
+
+func (api *testAPI) ScheduleQuery(uuid string, query string) (string, error) {
+	return "", models.ErrHostNotFound
+}
+
+func (api *testAPI) FetchResults(queryName string) (models.Rows, models.QueryStatus, error) {
+	return nil, models.QueryStatus{State: models.QueryUnknown}, models.ErrQueryNotFound
+}
+
+func testFactory(settings json.RawMessage, debug bool) (models.GoQueryAPI, error) {
+	return &testAPI{settings: string(settings), debug: debug}, nil
+}
+
+// expectPanic fails the test unless register panics
+func expectPanic(t *testing.T, description string, register func()) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("%s did not panic", description)
+		}
+	}()
+	register()
+}
+
+func TestRegisterPanicsOnMisuse(t *testing.T) {
+	Register("test-duplicate", testFactory)
+	expectPanic(t, "registering a name twice", func() { Register("test-duplicate", testFactory) })
+	expectPanic(t, "registering a nil factory", func() { Register("test-nil", nil) })
+	if containsName(Drivers(), "test-nil") {
+		t.Fatal("a nil factory was registered")
+	}
+}
+
+func TestCreate(t *testing.T) {
+	Register("test-create", testFactory)
+	if names := Drivers(); !sort.StringsAreSorted(names) || !containsName(names, "test-create") {
+		t.Fatalf("Drivers() = %v", names)
+	}
+
+	created, err := Create("test-create", nil, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if api := created.(*testAPI); api.settings != "{}" || !api.debug {
+		t.Fatalf("a driver without settings got %q, debug %v", api.settings, api.debug)
+	}
+	created, err = Create("test-create", json.RawMessage(`{"path": "/tmp"}`), false)
+	if err != nil || created.(*testAPI).settings != `{"path": "/tmp"}` {
+		t.Fatalf("Create() = %+v, %v", created, err)
+	}
+
+	tests := []struct {
+		name    string
+		message string
+	}{
+		{"", "No apiDriver is configured"},
+		{"test-unknown", "Unknown apiDriver 'test-unknown'"},
+	}
+	for _, test := range tests {
+		_, err := Create(test.name, nil, false)
+		if err == nil || !strings.Contains(err.Error(), test.message) || !strings.Contains(err.Error(), "test-create") {
+			t.Errorf("Create(%q) = %v, want %q listing the available drivers", test.name, err, test.message)
+		}
+	}
+}
+
+func containsName(names []string, name string) bool {
+	for _, candidate := range names {
+		if candidate == name {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,318 @@
+package fleet
+
+import (
+	"bytes"
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/models"
+
+	id "github.com/google/uuid"
+)
+
+// apiPrefix is where every Fleet endpoint used here lives
+const apiPrefix = "/api/latest/fleet"
+
+// defaultTimeout bounds requests made without a deadline, including live queries
+// scheduled without one. Fleet holds a live query open until the host answers or its
+// own live query timeout passes.
+const defaultTimeout = 2 * time.Minute
+
+// resultRetention is how long a finished live query can still be fetched, so .resume
+// works for a while, before it is forgotten
+const resultRetention = 10 * time.Minute
+
+// fleetCredentials are the server address and API token, read from a file or the fleet
+// driver block
+type fleetCredentials struct {
+	Server string `json:"server"`
+	Token  string `json:"token"`
+}
+
+// liveQuery is a query running or finished on a host, keyed by the name goquery knows it by
+type liveQuery struct {
+	done     bool
+	finished time.Time
+	rows     []map[string]string
+	message  string
+	// expired is set when the host didn't answer before the query's deadline
+	expired bool
+}
+
+type FleetAPI struct {
+	Client *http.Client
+	Server string
+	token  string
+
+	mutex sync.Mutex
+	// hostIDs maps host UUIDs to the ID Fleet uses for them in its API
+	hostIDs map[string]uint
+	queries map[string]*liveQuery
+}
+
+// CreateFleetAPI creates and returns an api implementation that implements the models.GoQueryAPI
+// interface using the Fleet server and API token in the credentials file at credentialsPath
+func CreateFleetAPI(credentialsPath string, developmentMode bool) (models.GoQueryAPI, error) {
+	credentialBytes, err := ioutil.ReadFile(strings.TrimSpace(credentialsPath))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading Fleet credential file: %s", err)
+	}
+	credentials := fleetCredentials{}
+	if err := json.Unmarshal(credentialBytes, &credentials); err != nil {
+		return nil, fmt.Errorf("Error parsing Fleet credential file: %s", err)
+	}
+	return newFleetAPI(credentials, developmentMode)
+}
+
+// newFleetAPI creates the API once the credentials are read, from a file or the driver's
+// config block
+func newFleetAPI(credentials fleetCredentials, developmentMode bool) (models.GoQueryAPI, error) {
+	if credentials.Server == "" || credentials.Token == "" {
+		return nil, fmt.Errorf("Fleet credentials must set server and token")
+	}
+
+	if developmentMode {
+		fmt.Println("Warning: developmentMode is enabled, setting InsecureSkipVerify: True for Fleet requests!")
+	}
+	tr := &http.Transport{
+		TLSClientConfig: &tls.Config{
+			InsecureSkipVerify: developmentMode,
+		},
+	}
+
+	return &FleetAPI{
+		Client:  &http.Client{Transport: tr},
+		Server:  strings.TrimRight(credentials.Server, "/"),
+		token:   credentials.Token,
+		hostIDs: make(map[string]uint),
+		queries: make(map[string]*liveQuery),
+	}, nil
+}
+
+// request sends a request to the Fleet API and returns the body of a successful response,
+// notFound is returned if Fleet answers 404
+func (instance *FleetAPI) request(ctx context.Context, method, path string, body interface{}, notFound error) ([]byte, error) {
+	if _, ok := ctx.Deadline(); !ok {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, defaultTimeout)
+		defer cancel()
+	}
+	bodyJSON := []byte{}
+	if body != nil {
+		bodyJSON, _ = json.Marshal(body)
+	}
+
+	request, err := http.NewRequestWithContext(ctx, method, instance.Server+path, bytes.NewReader(bodyJSON))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", instance.token))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("User-Agent", "goquery/1.0")
+	response, err := instance.Client.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode == 401 || response.StatusCode == 403 {
+		return nil, models.ErrUnauthenticated
+	}
+	if response.StatusCode == 404 {
+		return nil, notFound
+	}
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("Server returned unknown error: %d", response.StatusCode)
+	}
+
+	bodyBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read response")
+	}
+	return bodyBytes, nil
+}
+
+func (instance *FleetAPI) CheckHost(uuid string) (hosts.Host, error) {
+	return instance.CheckHostContext(context.Background(), uuid)
+}
+
+// CheckHostContext looks a host up by anything Fleet accepts as an identifier, such as
+// its UUID or hostname
+func (instance *FleetAPI) CheckHostContext(ctx context.Context, uuid string) (hosts.Host, error) {
+	type APIHost struct {
+		ID             uint   `json:"id"`
+		UUID           string `json:"uuid"`
+		Hostname       string `json:"hostname"`
+		ComputerName   string `json:"computer_name"`
+		Platform       string `json:"platform"`
+		OSVersion      string `json:"os_version"`
+		OsqueryVersion string `json:"osquery_version"`
+	}
+	type HostResponse struct {
+		Host APIHost `json:"host"`
+	}
+
+	bodyBytes, err := instance.request(ctx, "GET", apiPrefix+"/hosts/identifier/"+url.PathEscape(uuid), nil, models.ErrHostNotFound)
+	if err != nil {
+		return hosts.Host{}, fmt.Errorf("CheckHost call failed: %w", err)
+	}
+	hostResponse := HostResponse{}
+	if err := json.Unmarshal(bodyBytes, &hostResponse); err != nil {
+		return hosts.Host{}, fmt.Errorf("Could not parse host response: %s", err)
+	}
+
+	host := hostResponse.Host
+	computerName := host.ComputerName
+	if computerName == "" {
+		computerName = host.Hostname
+	}
+	// Remember the ID under the identifier used as well so ScheduleQuery accepts either
+	instance.mutex.Lock()
+	instance.hostIDs[host.UUID] = host.ID
+	instance.hostIDs[uuid] = host.ID
+	instance.mutex.Unlock()
+
+	return hosts.Host{
+		UUID:             host.UUID,
+		ComputerName:     computerName,
+		Platform:         fmt.Sprintf("%s (%s)", host.Platform, host.OSVersion),
+		Version:          host.OsqueryVersion,
+		CurrentDirectory: "/",
+	}, nil
+}
+
+func (instance *FleetAPI) ScheduleQuery(uuid string, query string) (string, error) {
+	return instance.ScheduleQueryContext(context.Background(), uuid, query)
+}
+
+// ScheduleQueryContext starts a live query on the host. Fleet only answers once the host
+// has, so the request is made in the background and FetchResults reports the query as
+// pending until it returns. The live query keeps running after ctx is cancelled, since
+// queries from .schedule outlive the command, but gives up at ctx's deadline.
+//
+// This uses Fleet's single host live query endpoint rather than a live query campaign.
+// Campaign results are only delivered over Fleet's websocket, and goquery already runs
+// one query per host and fans out across hosts itself.
+func (instance *FleetAPI) ScheduleQueryContext(ctx context.Context, uuid string, query string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	instance.mutex.Lock()
+	hostID, ok := instance.hostIDs[uuid]
+	instance.mutex.Unlock()
+	if !ok {
+		if _, err := instance.CheckHostContext(ctx, uuid); err != nil {
+			return "", err
+		}
+		instance.mutex.Lock()
+		hostID, ok = instance.hostIDs[uuid]
+		instance.mutex.Unlock()
+		if !ok {
+			return "", models.ErrHostNotFound
+		}
+	}
+
+	queryName := id.New().String()
+	running := &liveQuery{}
+	instance.mutex.Lock()
+	instance.evictLocked()
+	instance.queries[queryName] = running
+	instance.mutex.Unlock()
+
+	// The query outlives ctx, which only covers scheduling it, but not its deadline
+	liveCtx, cancel := context.Background(), func() {}
+	if deadline, ok := ctx.Deadline(); ok {
+		liveCtx, cancel = context.WithDeadline(liveCtx, deadline)
+	}
+	go func() {
+		defer cancel()
+		instance.runLiveQuery(liveCtx, hostID, query, running)
+	}()
+	return queryName, nil
+}
+
+func (instance *FleetAPI) runLiveQuery(ctx context.Context, hostID uint, query string, running *liveQuery) {
+	type LiveQueryRequest struct {
+		Query string `json:"query"`
+	}
+	type LiveQueryResponse struct {
+		Status string              `json:"status"`
+		Error  *string             `json:"error"`
+		Rows   []map[string]string `json:"rows"`
+	}
+
+	rows := []map[string]string{}
+	message := ""
+	expired := false
+	bodyBytes, err := instance.request(ctx, "POST", fmt.Sprintf("%s/hosts/%d/query", apiPrefix, hostID), LiveQueryRequest{Query: query}, models.ErrHostNotFound)
+	if err != nil && ctx.Err() == context.DeadlineExceeded {
+		message = "The host didn't answer before the query deadline"
+		expired = true
+	} else if err != nil {
+		message = err.Error()
+	} else {
+		liveResponse := LiveQueryResponse{}
+		if err := json.Unmarshal(bodyBytes, &liveResponse); err != nil {
+			message = fmt.Sprintf("Could not parse live query response: %s", err)
+		} else if liveResponse.Error != nil {
+			message = *liveResponse.Error
+		} else if liveResponse.Status != "" && liveResponse.Status != "online" {
+			message = fmt.Sprintf("Host is %s", liveResponse.Status)
+		} else if liveResponse.Rows != nil {
+			rows = liveResponse.Rows
+		}
+	}
+
+	instance.mutex.Lock()
+	running.done = true
+	running.finished = time.Now()
+	running.rows = rows
+	running.message = message
+	running.expired = expired
+	instance.mutex.Unlock()
+}
+
+func (instance *FleetAPI) FetchResults(queryName string) ([]map[string]string, models.QueryStatus, error) {
+	return instance.FetchResultsContext(context.Background(), queryName)
+}
+
+// FetchResultsContext never contacts Fleet, it reports on a live query started by ScheduleQuery
+func (instance *FleetAPI) FetchResultsContext(ctx context.Context, queryName string) ([]map[string]string, models.QueryStatus, error) {
+	instance.mutex.Lock()
+	defer instance.mutex.Unlock()
+	instance.evictLocked()
+
+	running, ok := instance.queries[queryName]
+	if !ok {
+		return []map[string]string{}, models.QueryStatus{State: models.QueryUnknown}, models.ErrQueryNotFound
+	}
+	if !running.done {
+		return []map[string]string{}, models.QueryStatus{State: models.QueryPending}, nil
+	}
+	if running.expired {
+		return []map[string]string{}, models.QueryStatus{State: models.QueryExpired, Message: running.message}, nil
+	}
+	if running.message != "" {
+		return []map[string]string{}, models.QueryStatus{State: models.QueryFailed, Message: running.message}, nil
+	}
+	return running.rows, models.QueryStatus{State: models.QueryComplete}, nil
+}
+
+// evictLocked forgets live queries that finished more than resultRetention ago. The caller
+// must hold the lock.
+func (instance *FleetAPI) evictLocked() {
+	for queryName, running := range instance.queries {
+		if running.done && time.Since(running.finished) > resultRetention {
+			delete(instance.queries, queryName)
+		}
+	}
+}
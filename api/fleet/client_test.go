@@ -0,0 +1,246 @@
+package fleet
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/api"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// fakeFleet serves the Fleet endpoints the driver uses for a single host with ID 7
+func fakeFleet(t *testing.T) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch {
+		case r.Method == "GET" && r.URL.Path == apiPrefix+"/hosts/identifier/host-uuid",
+			r.Method == "GET" && r.URL.Path == apiPrefix+"/hosts/identifier/web01":
+			w.Write([]byte(`{"host": {"id": 7, "uuid": "host-uuid", "hostname": "web01", "platform": "ubuntu", "os_version": "22.04", "osquery_version": "5.9.1"}}`))
+		case r.Method == "POST" && r.URL.Path == apiPrefix+"/hosts/7/query":
+			request := struct {
+				Query string `json:"query"`
+			}{}
+			json.NewDecoder(r.Body).Decode(&request)
+			switch request.Query {
+			case "select 1 as one":
+				w.Write([]byte(`{"host_id": 7, "status": "online", "error": null, "rows": [{"one": "1"}]}`))
+			case "select * from missing":
+				w.Write([]byte(`{"host_id": 7, "status": "online", "error": "no such table: missing", "rows": null}`))
+			case "offline":
+				w.Write([]byte(`{"host_id": 7, "status": "offline", "error": null, "rows": null}`))
+			case "slow":
+				// Never answer, like a host that doesn't check in
+				select {
+				case <-r.Context().Done():
+				case <-time.After(5 * time.Second):
+				}
+				w.WriteHeader(http.StatusGatewayTimeout)
+			default:
+				w.WriteHeader(http.StatusInternalServerError)
+			}
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+}
+
+func createTestAPI(t *testing.T, server, token string) *FleetAPI {
+	dir, err := ioutil.TempDir("", "goquery-fleet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	credentialsPath := filepath.Join(dir, "fleet.json")
+	credentials, _ := json.Marshal(fleetCredentials{Server: server + "/", Token: token})
+	if err := ioutil.WriteFile(credentialsPath, credentials, 0600); err != nil {
+		t.Fatal(err)
+	}
+	api, err := CreateFleetAPI(credentialsPath, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api.(*FleetAPI)
+}
+
+// waitForQuery fetches the results of queryName until it is no longer pending
+func waitForQuery(t *testing.T, api *FleetAPI, queryName string) ([]map[string]string, models.QueryStatus) {
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		rows, status, err := api.FetchResults(queryName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !status.Pending() {
+			return rows, status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is still pending", queryName)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCreateFleetAPIChecksCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goquery-fleet")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	credentialsPath := filepath.Join(dir, "fleet.json")
+	ioutil.WriteFile(credentialsPath, []byte(`{"server": "https://fleet.example.com"}`), 0600)
+	if _, err := CreateFleetAPI(credentialsPath, false); err == nil {
+		t.Fatal("expected an error for credentials without a token")
+	}
+	if _, err := CreateFleetAPI(filepath.Join(dir, "missing.json"), false); err == nil {
+		t.Fatal("expected an error for a missing credentials file")
+	}
+}
+
+func TestDriverReadsTheCredentialsFromItsBlock(t *testing.T) {
+	server := fakeFleet(t)
+	defer server.Close()
+
+	settings, _ := json.Marshal(map[string]string{"server": server.URL, "token": "token"})
+	created, err := api.Create("fleet", settings, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := created.CheckHost("host-uuid"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := api.Create("fleet", json.RawMessage(`{"server": "https://fleet.example.com"}`), false); err == nil {
+		t.Fatal("expected an error for a block without a token")
+	}
+	if _, err := api.Create("fleet", json.RawMessage(`{"fleetCfgPath": "/nonexistent/fleet.json"}`), false); err == nil {
+		t.Fatal("expected an error for a missing credentials file")
+	}
+}
+
+func TestCheckHost(t *testing.T) {
+	server := fakeFleet(t)
+	defer server.Close()
+	api := createTestAPI(t, server.URL, "token")
+
+	for _, identifier := range []string{"host-uuid", "web01"} {
+		host, err := api.CheckHost(identifier)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if host.UUID != "host-uuid" || host.ComputerName != "web01" || host.Platform != "ubuntu (22.04)" || host.Version != "5.9.1" {
+			t.Fatalf("unexpected host: %+v", host)
+		}
+	}
+	if _, err := api.CheckHost("missing"); !errors.Is(err, models.ErrHostNotFound) {
+		t.Fatalf("CheckHost() of an unknown host = %v, want ErrHostNotFound", err)
+	}
+
+	unauthenticated := createTestAPI(t, server.URL, "wrong")
+	if _, err := unauthenticated.CheckHost("host-uuid"); !errors.Is(err, models.ErrUnauthenticated) {
+		t.Fatalf("CheckHost() with a bad token = %v, want ErrUnauthenticated", err)
+	}
+}
+
+func TestLiveQueries(t *testing.T) {
+	server := fakeFleet(t)
+	defer server.Close()
+	api := createTestAPI(t, server.URL, "token")
+
+	tests := []struct {
+		query   string
+		state   models.QueryState
+		rows    int
+		message string
+	}{
+		{"select 1 as one", models.QueryComplete, 1, ""},
+		{"select * from missing", models.QueryFailed, 0, "no such table: missing"},
+		{"offline", models.QueryFailed, 0, "Host is offline"},
+		{"select broken", models.QueryFailed, 0, "Server returned unknown error: 500"},
+	}
+	for _, test := range tests {
+		// The host is looked up on first use, by hostname here
+		queryName, err := api.ScheduleQuery("web01", test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		rows, status := waitForQuery(t, api, queryName)
+		if status.State != test.state || len(rows) != test.rows || !strings.Contains(status.Message, test.message) {
+			t.Errorf("%s: got %v rows, %+v", test.query, rows, status)
+		}
+	}
+	if _, err := api.ScheduleQuery("missing", "select 1"); !errors.Is(err, models.ErrHostNotFound) {
+		t.Fatalf("ScheduleQuery() on an unknown host = %v, want ErrHostNotFound", err)
+	}
+	if _, _, err := api.FetchResults("never-scheduled"); !errors.Is(err, models.ErrQueryNotFound) {
+		t.Fatalf("FetchResults() of an unknown query = %v, want ErrQueryNotFound", err)
+	}
+}
+
+func TestLiveQueriesUseTheSchedulingDeadline(t *testing.T) {
+	server := fakeFleet(t)
+	defer server.Close()
+	api := createTestAPI(t, server.URL, "token")
+
+	// Cancelling ctx once the query is scheduled doesn't stop it
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	queryName, err := api.ScheduleQueryContext(ctx, "host-uuid", "select 1 as one")
+	cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows, status := waitForQuery(t, api, queryName); status.State != models.QueryComplete || len(rows) != 1 {
+		t.Fatalf("query scheduled with a cancelled context got %v, %+v", rows, status)
+	}
+
+	// but its deadline does
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	queryName, err = api.ScheduleQueryContext(ctx, "host-uuid", "slow")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, status := waitForQuery(t, api, queryName); status.State != models.QueryExpired {
+		t.Fatalf("query past its deadline got %+v, want Expired", status)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := api.ScheduleQueryContext(cancelled, "host-uuid", "select 1 as one"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ScheduleQueryContext() with a cancelled context = %v", err)
+	}
+}
+
+func TestFinishedQueriesAreEvicted(t *testing.T) {
+	server := fakeFleet(t)
+	defer server.Close()
+	api := createTestAPI(t, server.URL, "token")
+
+	queryName, err := api.ScheduleQuery("host-uuid", "select 1 as one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	waitForQuery(t, api, queryName)
+	// Fetching again within resultRetention still works
+	waitForQuery(t, api, queryName)
+
+	api.mutex.Lock()
+	api.queries[queryName].finished = time.Now().Add(-resultRetention - time.Second)
+	api.mutex.Unlock()
+	if _, _, err := api.FetchResults(queryName); !errors.Is(err, models.ErrQueryNotFound) {
+		t.Fatalf("FetchResults() of an evicted query = %v, want ErrQueryNotFound", err)
+	}
+	if len(api.queries) != 0 {
+		t.Fatalf("%d queries left after eviction", len(api.queries))
+	}
+}
@@ -0,0 +1,30 @@
+package fleet
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AbGuthrie/goquery/v2/api"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// driverConfig is the fleet block in the drivers section of the goquery config
+type driverConfig struct {
+	fleetCredentials
+	// FleetCfgPath is the older way of configuring the driver, a credentials file read
+	// instead of the server and token in the block
+	FleetCfgPath string `json:"fleetCfgPath"`
+}
+
+func init() {
+	api.Register("fleet", func(settings json.RawMessage, debug bool) (models.GoQueryAPI, error) {
+		driverCfg := driverConfig{}
+		if err := json.Unmarshal(settings, &driverCfg); err != nil {
+			return nil, fmt.Errorf("Error parsing fleet driver config: %s", err)
+		}
+		if driverCfg.FleetCfgPath != "" {
+			return CreateFleetAPI(driverCfg.FleetCfgPath, debug)
+		}
+		return newFleetAPI(driverCfg.fleetCredentials, debug)
+	})
+}
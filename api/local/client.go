@@ -0,0 +1,245 @@
+// Package local is a goquery API for the machine goquery runs on. Queries are answered
+// by running osqueryi, optionally connected to the extensions socket of a running
+// osqueryd, and the machine is the only host.
+package local
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/models"
+
+	id "github.com/google/uuid"
+)
+
+// DefaultOsqueryiPath is looked up in PATH when osqueryiPath isn't configured
+const DefaultOsqueryiPath = "osqueryi"
+
+// queryTimeout bounds a single run of osqueryi when the query has no earlier deadline
+const queryTimeout = 10 * time.Minute
+
+// resultRetention is how long the results of a finished query can still be fetched, so
+// .resume works for a while, before they are forgotten
+const resultRetention = 10 * time.Minute
+
+const hostInfoQuery = "select s.uuid, s.computer_name, s.hostname, o.platform, o.version as os_version, " +
+	"i.version as osquery_version from system_info s, os_version o, osquery_info i"
+
+// localQuery is a query running or finished, keyed by the name goquery knows it by
+type localQuery struct {
+	done     bool
+	finished time.Time
+	rows     []map[string]string
+	message  string
+	// expired is set when osqueryi was stopped at the query's deadline
+	expired bool
+}
+
+type LocalAPI struct {
+	OsqueryiPath string
+	SocketPath   string
+	host         hosts.Host
+	// names are the names the local machine can be connected to by
+	names []string
+
+	mutex   sync.Mutex
+	queries map[string]*localQuery
+}
+
+// CreateLocalAPI creates and returns an api implementation that implements the models.GoQueryAPI
+// interface by running the osqueryi at osqueryiPath. If socketPath is set osqueryi connects
+// to the extensions socket of a running osqueryd instead of using its own tables.
+func CreateLocalAPI(osqueryiPath string, socketPath string) (models.GoQueryAPI, error) {
+	if osqueryiPath == "" {
+		osqueryiPath = DefaultOsqueryiPath
+	}
+	resolvedPath, err := exec.LookPath(osqueryiPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not find osqueryi: %s", err)
+	}
+	instance := &LocalAPI{
+		OsqueryiPath: resolvedPath,
+		SocketPath:   socketPath,
+		queries:      make(map[string]*localQuery),
+	}
+
+	// Ask osqueryi about the machine up front, which also checks it runs at all
+	ctx, cancel := context.WithTimeout(context.Background(), queryTimeout)
+	defer cancel()
+	rows, err := instance.run(ctx, hostInfoQuery)
+	if err != nil {
+		return nil, fmt.Errorf("Could not query the local machine: %s", err)
+	}
+	if len(rows) != 1 {
+		return nil, fmt.Errorf("Could not query the local machine: expected one row, osqueryi returned %d", len(rows))
+	}
+	info := rows[0]
+	computerName := info["computer_name"]
+	if computerName == "" {
+		computerName = info["hostname"]
+	}
+	instance.host = hosts.Host{
+		UUID:             info["uuid"],
+		ComputerName:     computerName,
+		Platform:         fmt.Sprintf("%s (%s)", info["platform"], info["os_version"]),
+		Version:          info["osquery_version"],
+		CurrentDirectory: "/",
+	}
+	instance.names = []string{"localhost", info["uuid"], info["computer_name"], info["hostname"]}
+	return instance, nil
+}
+
+// run runs query with osqueryi and returns its rows, values osqueryi prints as numbers
+// or other JSON types are converted to their JSON text
+func (instance *LocalAPI) run(ctx context.Context, query string) ([]map[string]string, error) {
+	args := []string{"--json"}
+	if instance.SocketPath != "" {
+		args = append(args, "--connect", instance.SocketPath)
+	}
+	args = append(args, query)
+
+	var stdout, stderr bytes.Buffer
+	command := exec.CommandContext(ctx, instance.OsqueryiPath, args...)
+	command.Stdout = &stdout
+	command.Stderr = &stderr
+	if err := command.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("osqueryi was stopped: %s", ctx.Err())
+		}
+		// osqueryi explains a bad query on stderr and exits non zero
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return nil, fmt.Errorf("%s", message)
+		}
+		return nil, err
+	}
+
+	// Some versions of osqueryi print nothing at all for a query without rows
+	if len(bytes.TrimSpace(stdout.Bytes())) == 0 {
+		return []map[string]string{}, nil
+	}
+	objects := make([]map[string]interface{}, 0)
+	decoder := json.NewDecoder(&stdout)
+	decoder.UseNumber()
+	if err := decoder.Decode(&objects); err != nil {
+		return nil, fmt.Errorf("Could not parse osqueryi output: %s", err)
+	}
+	rows := make([]map[string]string, 0, len(objects))
+	for _, object := range objects {
+		row := make(map[string]string, len(object))
+		for column, value := range object {
+			switch typed := value.(type) {
+			case string:
+				row[column] = typed
+			case nil:
+				row[column] = ""
+			default:
+				encoded, _ := json.Marshal(typed)
+				row[column] = string(encoded)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func (instance *LocalAPI) CheckHost(uuid string) (hosts.Host, error) {
+	return instance.CheckHostContext(context.Background(), uuid)
+}
+
+// CheckHostContext finds the local machine by its UUID, computer name, hostname or localhost
+func (instance *LocalAPI) CheckHostContext(ctx context.Context, uuid string) (hosts.Host, error) {
+	for _, name := range instance.names {
+		if name != "" && strings.EqualFold(name, uuid) {
+			return instance.host, nil
+		}
+	}
+	return hosts.Host{}, fmt.Errorf("CheckHost call failed: %w", models.ErrHostNotFound)
+}
+
+func (instance *LocalAPI) ScheduleQuery(uuid string, query string) (string, error) {
+	return instance.ScheduleQueryContext(context.Background(), uuid, query)
+}
+
+// ScheduleQueryContext starts osqueryi in the background, FetchResults reports the query
+// as pending until it exits. osqueryi keeps running after ctx is cancelled, since queries
+// from .schedule outlive the command, but is killed at ctx's deadline or after
+// queryTimeout, whichever comes first.
+func (instance *LocalAPI) ScheduleQueryContext(ctx context.Context, uuid string, query string) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	if _, err := instance.CheckHostContext(ctx, uuid); err != nil {
+		return "", err
+	}
+
+	queryName := id.New().String()
+	running := &localQuery{}
+	instance.mutex.Lock()
+	instance.evictLocked()
+	instance.queries[queryName] = running
+	instance.mutex.Unlock()
+
+	deadline := time.Now().Add(queryTimeout)
+	if ctxDeadline, ok := ctx.Deadline(); ok && ctxDeadline.Before(deadline) {
+		deadline = ctxDeadline
+	}
+	runCtx, cancel := context.WithDeadline(context.Background(), deadline)
+	go func() {
+		defer cancel()
+		rows, err := instance.run(runCtx, query)
+
+		instance.mutex.Lock()
+		defer instance.mutex.Unlock()
+		running.done = true
+		running.finished = time.Now()
+		running.rows = rows
+		if err != nil {
+			running.message = err.Error()
+			running.expired = runCtx.Err() == context.DeadlineExceeded
+		}
+	}()
+	return queryName, nil
+}
+
+func (instance *LocalAPI) FetchResults(queryName string) ([]map[string]string, models.QueryStatus, error) {
+	return instance.FetchResultsContext(context.Background(), queryName)
+}
+
+// FetchResultsContext reports on a query started by ScheduleQuery
+func (instance *LocalAPI) FetchResultsContext(ctx context.Context, queryName string) ([]map[string]string, models.QueryStatus, error) {
+	instance.mutex.Lock()
+	defer instance.mutex.Unlock()
+
+	instance.evictLocked()
+	running, ok := instance.queries[queryName]
+	if !ok {
+		return []map[string]string{}, models.QueryStatus{State: models.QueryUnknown}, models.ErrQueryNotFound
+	}
+	if !running.done {
+		return []map[string]string{}, models.QueryStatus{State: models.QueryPending}, nil
+	}
+	if running.expired {
+		return []map[string]string{}, models.QueryStatus{State: models.QueryExpired, Message: running.message}, nil
+	}
+	if running.message != "" {
+		return []map[string]string{}, models.QueryStatus{State: models.QueryFailed, Message: running.message}, nil
+	}
+	return running.rows, models.QueryStatus{State: models.QueryComplete}, nil
+}
+
+// evictLocked forgets queries that finished more than resultRetention ago, the caller
+// must hold the mutex
+func (instance *LocalAPI) evictLocked() {
+	for queryName, running := range instance.queries {
+		if running.done && time.Since(running.finished) > resultRetention {
+			delete(instance.queries, queryName)
+		}
+	}
+}
@@ -0,0 +1,174 @@
+package local
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// fakeOsqueryiEnv makes the test binary act as osqueryi when it is set, so tests can
+// point osqueryiPath at os.Args[0]
+const fakeOsqueryiEnv = "GOQUERY_FAKE_OSQUERYI"
+
+func TestMain(m *testing.M) {
+	// osqueryi's flags aren't test flags, so this has to run before m.Run parses them
+	if os.Getenv(fakeOsqueryiEnv) != "" {
+		os.Exit(fakeOsqueryi(os.Args[len(os.Args)-1]))
+	}
+	os.Exit(m.Run())
+}
+
+// fakeOsqueryi answers query like osqueryi --json would and returns the exit code
+func fakeOsqueryi(query string) int {
+	switch query {
+	case hostInfoQuery:
+		fmt.Println(`[{"uuid": "local-uuid", "computer_name": "laptop", "hostname": "laptop.local", "platform": "darwin", "os_version": "14.0", "osquery_version": "5.9.1"}]`)
+	case "select 1 as one":
+		fmt.Println(`[{"one": 1, "empty": null, "name": "a"}]`)
+	case "select * from missing":
+		fmt.Fprintln(os.Stderr, "Error: no such table: missing")
+		return 1
+	case "garbage":
+		fmt.Println("this is not json")
+	case "nap":
+		time.Sleep(500 * time.Millisecond)
+		fmt.Println(`[{"rested": "yes"}]`)
+	case "sleep":
+		time.Sleep(time.Minute)
+	}
+	return 0
+}
+
+func createTestAPI(t *testing.T) *LocalAPI {
+	os.Setenv(fakeOsqueryiEnv, "1")
+	api, err := CreateLocalAPI(os.Args[0], "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return api.(*LocalAPI)
+}
+
+// waitForQuery fetches the results of queryName until it is no longer pending
+func waitForQuery(t *testing.T, api *LocalAPI, queryName string) ([]map[string]string, models.QueryStatus) {
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		rows, status, err := api.FetchResults(queryName)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !status.Pending() {
+			return rows, status
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%s is still pending", queryName)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestCreateLocalAPIDescribesTheMachine(t *testing.T) {
+	api := createTestAPI(t)
+	defer os.Unsetenv(fakeOsqueryiEnv)
+	for _, name := range []string{"localhost", "local-uuid", "LAPTOP", "laptop.local"} {
+		host, err := api.CheckHost(name)
+		if err != nil {
+			t.Fatalf("CheckHost(%s) = %v", name, err)
+		}
+		if host.UUID != "local-uuid" || host.ComputerName != "laptop" || host.Platform != "darwin (14.0)" || host.Version != "5.9.1" {
+			t.Fatalf("unexpected host: %+v", host)
+		}
+	}
+	if _, err := api.CheckHost("elsewhere"); !errors.Is(err, models.ErrHostNotFound) {
+		t.Fatalf("CheckHost() of another host = %v, want ErrHostNotFound", err)
+	}
+}
+
+func TestLocalQueries(t *testing.T) {
+	api := createTestAPI(t)
+	defer os.Unsetenv(fakeOsqueryiEnv)
+
+	queryName, err := api.ScheduleQuery("localhost", "select 1 as one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rows, status := waitForQuery(t, api, queryName)
+	if status.State != models.QueryComplete || len(rows) != 1 {
+		t.Fatalf("got %v, %+v", rows, status)
+	}
+	if rows[0]["one"] != "1" || rows[0]["empty"] != "" || rows[0]["name"] != "a" {
+		t.Fatalf("values were not converted to strings: %v", rows[0])
+	}
+
+	tests := []struct {
+		query   string
+		message string
+	}{
+		{"select * from missing", "Error: no such table: missing"},
+		{"garbage", "Could not parse osqueryi output"},
+	}
+	for _, test := range tests {
+		queryName, err := api.ScheduleQuery("localhost", test.query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, status := waitForQuery(t, api, queryName); status.State != models.QueryFailed || !strings.Contains(status.Message, test.message) {
+			t.Errorf("%s: got %+v, want a failure with %q", test.query, status, test.message)
+		}
+	}
+}
+
+func TestOsqueryiOutlivesTheSchedulingContext(t *testing.T) {
+	api := createTestAPI(t)
+	defer os.Unsetenv(fakeOsqueryiEnv)
+
+	// Cancelling ctx once the query is scheduled, as .schedule does, doesn't stop it
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	queryName, err := api.ScheduleQueryContext(ctx, "localhost", "nap")
+	cancel()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if rows, status := waitForQuery(t, api, queryName); status.State != models.QueryComplete || len(rows) != 1 {
+		t.Fatalf("got %v, %+v, want the query to finish", rows, status)
+	}
+
+	// but its deadline does
+	ctx, cancel = context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+	queryName, err = api.ScheduleQueryContext(ctx, "localhost", "sleep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, status := waitForQuery(t, api, queryName); status.State != models.QueryExpired || !strings.Contains(status.Message, "osqueryi was stopped") {
+		t.Fatalf("got %+v, want osqueryi to be stopped at the deadline", status)
+	}
+
+	cancelled, cancel := context.WithCancel(context.Background())
+	cancel()
+	if _, err := api.ScheduleQueryContext(cancelled, "localhost", "select 1 as one"); !errors.Is(err, context.Canceled) {
+		t.Fatalf("ScheduleQueryContext() with a cancelled context = %v", err)
+	}
+}
+
+func TestFinishedQueriesAreEvicted(t *testing.T) {
+	api := createTestAPI(t)
+	defer os.Unsetenv(fakeOsqueryiEnv)
+
+	queryName, _ := api.ScheduleQuery("localhost", "select 1 as one")
+	waitForQuery(t, api, queryName)
+	api.mutex.Lock()
+	api.queries[queryName].finished = time.Now().Add(-resultRetention - time.Second)
+	api.mutex.Unlock()
+	if _, _, err := api.FetchResults(queryName); !errors.Is(err, models.ErrQueryNotFound) {
+		t.Fatalf("FetchResults() of an evicted query = %v, want ErrQueryNotFound", err)
+	}
+	if len(api.queries) != 0 {
+		t.Fatalf("%d queries left after eviction", len(api.queries))
+	}
+}
@@ -0,0 +1,25 @@
+package local
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AbGuthrie/goquery/v2/api"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// driverConfig is the local block in the drivers section of the goquery config
+type driverConfig struct {
+	OsqueryiPath string `json:"osqueryiPath"`
+	SocketPath   string `json:"socketPath"`
+}
+
+func init() {
+	api.Register("local", func(settings json.RawMessage, debug bool) (models.GoQueryAPI, error) {
+		driverCfg := driverConfig{}
+		if err := json.Unmarshal(settings, &driverCfg); err != nil {
+			return nil, fmt.Errorf("Error parsing local driver config: %s", err)
+		}
+		return CreateLocalAPI(driverCfg.OsqueryiPath, driverCfg.SocketPath)
+	})
+}
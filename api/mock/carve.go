@@ -0,0 +1,94 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"strconv"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// StartCarve implements models.FileCarver
+func (instance *MockAPI) StartCarve(ctx context.Context, uuid string, path string) (string, error) {
+	type StartCarveResponse struct {
+		CarveID string `json:"carveId"`
+	}
+	bodyBytes, err := instance.carveRequest(ctx, "startCarve", url.Values{"uuid": {uuid}, "path": {path}}, models.ErrHostNotFound)
+	if err != nil {
+		return "", err
+	}
+	response := StartCarveResponse{}
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		instance.invalidate()
+		return "", fmt.Errorf("%w: %s", models.ErrUnauthenticated, err)
+	}
+	return response.CarveID, nil
+}
+
+// CarveStatus implements models.FileCarver
+func (instance *MockAPI) CarveStatus(ctx context.Context, carveID string) (models.CarveStatus, error) {
+	type CarveStatusResponse struct {
+		Started        bool   `json:"started"`
+		BlockCount     int    `json:"blockCount"`
+		BlocksReceived int    `json:"blocksReceived"`
+		CarveSize      int64  `json:"carveSize"`
+		Error          string `json:"error"`
+	}
+	bodyBytes, err := instance.carveRequest(ctx, "carveStatus", url.Values{"carveId": {carveID}}, models.ErrQueryNotFound)
+	if err != nil {
+		return models.CarveStatus{}, err
+	}
+	response := CarveStatusResponse{}
+	if err := json.Unmarshal(bodyBytes, &response); err != nil {
+		instance.invalidate()
+		return models.CarveStatus{}, fmt.Errorf("%w: %s", models.ErrUnauthenticated, err)
+	}
+	return models.CarveStatus{
+		Started:        response.Started,
+		BlockCount:     response.BlockCount,
+		BlocksReceived: response.BlocksReceived,
+		CarveSize:      response.CarveSize,
+		Error:          response.Error,
+	}, nil
+}
+
+// FetchCarveBlock implements models.FileCarver
+func (instance *MockAPI) FetchCarveBlock(ctx context.Context, carveID string, blockID int) ([]byte, error) {
+	return instance.carveRequest(ctx, "carveBlock", url.Values{
+		"carveId": {carveID},
+		"blockId": {strconv.Itoa(blockID)},
+	}, models.ErrQueryNotFound)
+}
+
+// carveRequest posts to one of the mock server's carve endpoints and returns the body,
+// notFound is returned if the server doesn't know the host or carve
+func (instance *MockAPI) carveRequest(ctx context.Context, endpoint string, data url.Values, notFound error) ([]byte, error) {
+	if err := instance.ensureAuthenticated(ctx); err != nil {
+		return nil, err
+	}
+
+	response, err := instance.postForm(ctx, "https://localhost:8001/"+endpoint, data)
+	if err != nil {
+		return nil, fmt.Errorf("%s call failed: %s", endpoint, err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode == 401 {
+		instance.invalidate()
+		return nil, models.ErrUnauthenticated
+	}
+	if response.StatusCode == 404 {
+		return nil, notFound
+	}
+	if response.StatusCode != 200 {
+		return nil, fmt.Errorf("Server returned unknown error: %d", response.StatusCode)
+	}
+
+	bodyBytes, err := ioutil.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read %s response", endpoint)
+	}
+	return bodyBytes, nil
+}
@@ -2,6 +2,7 @@ package mock
 
 import (
 	"bufio"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -10,7 +11,9 @@ import (
 	"net/http/cookiejar"
 	"net/url"
 	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -26,6 +29,10 @@ type MockAPI struct {
 	Client          *http.Client
 	Authed          bool
 	DevelopmentMode bool
+
+	// authMutex guards Authed, queries fanned out to many hosts run concurrently and only
+	// one of them should prompt for credentials
+	authMutex sync.Mutex
 }
 
 // CreateMockAPI creates and returns an api implementation that implements the models.GoQueryAPI interface
@@ -148,7 +155,7 @@ func (instance *MockAPI) authenticate() error {
 	)
 
 	if instance.DevelopmentMode {
-		fmt.Printf("samlResponse: %s\nrelayState: %s\n", response, relayState)
+		fmt.Printf("samlResponse: %v\nrelayState: %s\n", response, relayState)
 	}
 
 	if err != nil {
@@ -160,13 +167,49 @@ func (instance *MockAPI) authenticate() error {
 	return nil
 }
 
-func (instance *MockAPI) CheckHost(uuid string) (hosts.Host, error) {
+// ensureAuthenticated logs in unless already authenticated. Concurrent callers wait for
+// the first one to finish logging in. Nobody is prompted once ctx is done, for example
+// after Ctrl-C.
+func (instance *MockAPI) ensureAuthenticated(ctx context.Context) error {
+	instance.authMutex.Lock()
+	defer instance.authMutex.Unlock()
 	if !instance.Authed {
-		err := instance.authenticate()
-		if err != nil {
-			return hosts.Host{}, err
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := instance.authenticate(); err != nil {
+			return fmt.Errorf("%w: %s", models.ErrUnauthenticated, err)
 		}
 	}
+	return nil
+}
+
+// invalidate makes the next call log in again. It is only called when the server rejects
+// the session, a failed or cancelled request says nothing about it.
+func (instance *MockAPI) invalidate() {
+	instance.authMutex.Lock()
+	instance.Authed = false
+	instance.authMutex.Unlock()
+}
+
+// postForm is http.Client.PostForm bound to ctx so the request is abandoned when ctx is done
+func (instance *MockAPI) postForm(ctx context.Context, endpoint string, data url.Values) (*http.Response, error) {
+	request, err := http.NewRequestWithContext(ctx, "POST", endpoint, strings.NewReader(data.Encode()))
+	if err != nil {
+		return nil, err
+	}
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	return instance.Client.Do(request)
+}
+
+func (instance *MockAPI) CheckHost(uuid string) (hosts.Host, error) {
+	return instance.CheckHostContext(context.Background(), uuid)
+}
+
+func (instance *MockAPI) CheckHostContext(ctx context.Context, uuid string) (hosts.Host, error) {
+	if err := instance.ensureAuthenticated(ctx); err != nil {
+		return hosts.Host{}, err
+	}
 	type APIHost struct {
 		UUID           string `json:"UUID"`
 		ComputerName   string `json:"ComputerName"`
@@ -175,15 +218,18 @@ func (instance *MockAPI) CheckHost(uuid string) (hosts.Host, error) {
 		Version        string `json:"Version"`
 	}
 
-	response, err := instance.Client.PostForm("https://localhost:8001/checkHost",
+	response, err := instance.postForm(ctx, "https://localhost:8001/checkHost",
 		url.Values{"uuid": {uuid}},
 	)
 	if err != nil {
-		// Possible Authentication Failure
 		return hosts.Host{}, fmt.Errorf("CheckHost call failed: %s", err)
 	}
+	if response.StatusCode == 401 {
+		instance.invalidate()
+		return hosts.Host{}, models.ErrUnauthenticated
+	}
 	if response.StatusCode == 404 {
-		return hosts.Host{}, fmt.Errorf("Unknown Host")
+		return hosts.Host{}, models.ErrHostNotFound
 	}
 	if response.StatusCode != 200 {
 		return hosts.Host{}, fmt.Errorf("Server returned unknown error: %d", response.StatusCode)
@@ -200,8 +246,8 @@ func (instance *MockAPI) CheckHost(uuid string) (hosts.Host, error) {
 			fmt.Printf("Returned Body: %s\n", string(bodyBytes))
 		}
 		// Probable authentication failure
-		instance.Authed = false
-		return hosts.Host{}, err
+		instance.invalidate()
+		return hosts.Host{}, fmt.Errorf("%w: %s", models.ErrUnauthenticated, err)
 	}
 
 	return hosts.Host{
@@ -214,27 +260,31 @@ func (instance *MockAPI) CheckHost(uuid string) (hosts.Host, error) {
 }
 
 func (instance *MockAPI) ScheduleQuery(uuid string, query string) (string, error) {
-	if !instance.Authed {
-		err := instance.authenticate()
-		if err != nil {
-			return "", err
-		}
+	return instance.ScheduleQueryContext(context.Background(), uuid, query)
+}
+
+func (instance *MockAPI) ScheduleQueryContext(ctx context.Context, uuid string, query string) (string, error) {
+	if err := instance.ensureAuthenticated(ctx); err != nil {
+		return "", err
 	}
 	type QueryScheduleResponse struct {
 		QueryName string `json:"queryName"`
 	}
 
-	response, err := instance.Client.PostForm("https://localhost:8001/scheduleQuery",
+	response, err := instance.postForm(ctx, "https://localhost:8001/scheduleQuery",
 		url.Values{
 			"uuid":  {uuid},
 			"query": {query}},
 	)
 	if err != nil {
-		instance.authenticate()
 		return "", fmt.Errorf("ScheduleQuery call failed: %s", err)
 	}
+	if response.StatusCode == 401 {
+		instance.invalidate()
+		return "", models.ErrUnauthenticated
+	}
 	if response.StatusCode == 404 {
-		return "", fmt.Errorf("Unknown Host")
+		return "", models.ErrHostNotFound
 	}
 	if response.StatusCode != 200 {
 		return "", fmt.Errorf("Server returned unknown error: %d", response.StatusCode)
@@ -247,53 +297,74 @@ func (instance *MockAPI) ScheduleQuery(uuid string, query string) (string, error
 	qsResponse := QueryScheduleResponse{}
 	err = json.Unmarshal(bodyBytes, &qsResponse)
 	if err != nil {
-		instance.Authed = false
-		return "", err
+		instance.invalidate()
+		return "", fmt.Errorf("%w: %s", models.ErrUnauthenticated, err)
 	}
-	hosts.AddQueryToHost(uuid, hosts.Query{Name: qsResponse.QueryName, SQL: query})
 	return qsResponse.QueryName, nil
 }
 
-func (instance *MockAPI) FetchResults(queryName string) ([]map[string]string, string, error) {
+func (instance *MockAPI) FetchResults(queryName string) ([]map[string]string, models.QueryStatus, error) {
+	return instance.FetchResultsContext(context.Background(), queryName)
+}
+
+func (instance *MockAPI) FetchResultsContext(ctx context.Context, queryName string) ([]map[string]string, models.QueryStatus, error) {
+	return instance.fetchResults(ctx, url.Values{"queryName": {queryName}})
+}
+
+// FetchResultsPage implements models.GoQueryAPIPager
+func (instance *MockAPI) FetchResultsPage(ctx context.Context, queryName string, offset, limit int) ([]map[string]string, models.QueryStatus, error) {
+	return instance.fetchResults(ctx, url.Values{
+		"queryName": {queryName},
+		"offset":    {strconv.Itoa(offset)},
+		"limit":     {strconv.Itoa(limit)},
+	})
+}
+
+func (instance *MockAPI) fetchResults(ctx context.Context, data url.Values) ([]map[string]string, models.QueryStatus, error) {
 	type ResultsResponse struct {
-		Rows   []map[string]string `json:"results"`
-		Status string              `json:"status"`
+		Rows    []map[string]string `json:"results"`
+		Status  string              `json:"status"`
+		Message string              `json:"message"`
 	}
 	resultsResponse := ResultsResponse{}
+	unknown := models.QueryStatus{State: models.QueryUnknown}
 
-	if !instance.Authed {
-		err := instance.authenticate()
-		if err != nil {
-			return resultsResponse.Rows, "", err
-		}
+	if err := instance.ensureAuthenticated(ctx); err != nil {
+		return resultsResponse.Rows, unknown, err
 	}
 
-	response, err := instance.Client.PostForm(
+	response, err := instance.postForm(
+		ctx,
 		"https://localhost:8001/fetchResults",
-		url.Values{"queryName": {queryName}},
+		data,
 	)
 
 	if err != nil {
-		instance.authenticate()
-		return resultsResponse.Rows, "", fmt.Errorf("FetchResults call failed: %s", err)
+		return resultsResponse.Rows, unknown, fmt.Errorf("FetchResults call failed: %s", err)
+	}
+	if response.StatusCode == 401 {
+		instance.invalidate()
+		return resultsResponse.Rows, unknown, models.ErrUnauthenticated
 	}
 	if response.StatusCode == 404 {
-		return resultsResponse.Rows, "", fmt.Errorf("Unknown queryName")
+		return resultsResponse.Rows, unknown, models.ErrQueryNotFound
 	}
 	if response.StatusCode != 200 {
-		return resultsResponse.Rows, "", fmt.Errorf("Server returned unknown error: %d", response.StatusCode)
+		return resultsResponse.Rows, unknown, fmt.Errorf("Server returned unknown error: %d", response.StatusCode)
 	}
 
 	bodyBytes, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return resultsResponse.Rows, "", fmt.Errorf("Could not read fetchResults response")
+		return resultsResponse.Rows, unknown, fmt.Errorf("Could not read fetchResults response")
 	}
 
 	if err := json.Unmarshal(bodyBytes, &resultsResponse); err != nil {
-		instance.Authed = false
-		return resultsResponse.Rows, "", err
+		instance.invalidate()
+		return resultsResponse.Rows, unknown, fmt.Errorf("%w: %s", models.ErrUnauthenticated, err)
 	}
 
-	// Return QueryResultsResponse type (outer caller should check .Status)
-	return resultsResponse.Rows, resultsResponse.Status, nil
+	return resultsResponse.Rows, models.QueryStatus{
+		State:   models.ParseQueryState(resultsResponse.Status),
+		Message: resultsResponse.Message,
+	}, nil
 }
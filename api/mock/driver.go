@@ -0,0 +1,15 @@
+package mock
+
+import (
+	"encoding/json"
+
+	"github.com/AbGuthrie/goquery/v2/api"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func init() {
+	// The mock backend has nothing to configure
+	api.Register("mock", func(settings json.RawMessage, debug bool) (models.GoQueryAPI, error) {
+		return CreateMockAPI(debug)
+	})
+}
@@ -3,6 +3,7 @@ package osctrl
 import (
 	"bufio"
 	"bytes"
+	"context"
 	"crypto/tls"
 	"encoding/json"
 	"fmt"
@@ -11,6 +12,7 @@ import (
 	"net/http/cookiejar"
 	"os"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 
@@ -32,6 +34,9 @@ type OSctrlAPI struct {
 	CookieJar *cookiejar.Jar
 	Client    *http.Client
 	Authed    bool
+	// authMutex guards Token and Authed, queries fanned out to many hosts run concurrently
+	// and only one of them should prompt for credentials
+	authMutex sync.Mutex
 
 	Protocol  string
 	Server    string
@@ -129,13 +134,36 @@ func (instance *OSctrlAPI) authenticate() error {
 	return nil
 }
 
-func (instance *OSctrlAPI) CheckHost(uuid string) (hosts.Host, error) {
+// ensureAuthenticated logs in unless already authenticated and returns the token to use.
+// Concurrent callers wait for the first one to finish logging in.
+func (instance *OSctrlAPI) ensureAuthenticated() (string, error) {
+	instance.authMutex.Lock()
+	defer instance.authMutex.Unlock()
 	if !instance.Authed {
-		err := instance.authenticate()
-		if err != nil {
-			return hosts.Host{}, err
+		if err := instance.authenticate(); err != nil {
+			return "", fmt.Errorf("%w: %s", models.ErrUnauthenticated, err)
 		}
 	}
+	return instance.Token.Token, nil
+}
+
+// invalidate makes the next call log in again. It is only called when the server rejects
+// the token, a failed or cancelled request says nothing about it.
+func (instance *OSctrlAPI) invalidate() {
+	instance.authMutex.Lock()
+	instance.Authed = false
+	instance.authMutex.Unlock()
+}
+
+func (instance *OSctrlAPI) CheckHost(uuid string) (hosts.Host, error) {
+	return instance.CheckHostContext(context.Background(), uuid)
+}
+
+func (instance *OSctrlAPI) CheckHostContext(ctx context.Context, uuid string) (hosts.Host, error) {
+	token, err := instance.ensureAuthenticated()
+	if err != nil {
+		return hosts.Host{}, err
+	}
 	type APIHost struct {
 		ComputerName   string `json:"Localname"`
 		HostIdentifier string `json:"HostIdentifier"`
@@ -145,8 +173,8 @@ func (instance *OSctrlAPI) CheckHost(uuid string) (hosts.Host, error) {
 		Version        string `json:"OsqueryVersion"`
 	}
 
-	request, _ := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/nodes/%s", instance.APIBase, uuid), nil)
-	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", instance.Token.Token))
+	request, _ := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/nodes/%s", instance.APIBase, uuid), nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 	request.Header.Set("User-Agent", "goquery/1.0")
 	response, err := instance.Client.Do(request)
 
@@ -155,6 +183,10 @@ func (instance *OSctrlAPI) CheckHost(uuid string) (hosts.Host, error) {
 		return hosts.Host{}, fmt.Errorf("CheckHost call failed: %s", err)
 	}
 
+	if response.StatusCode == 401 || response.StatusCode == 403 {
+		instance.invalidate()
+		return hosts.Host{}, models.ErrUnauthenticated
+	}
 	if response.StatusCode != 200 {
 		return hosts.Host{}, fmt.Errorf("Server returned unknown error: %d", response.StatusCode)
 	}
@@ -162,7 +194,7 @@ func (instance *OSctrlAPI) CheckHost(uuid string) (hosts.Host, error) {
 	contentLength := response.Header["Content-Length"]
 
 	if len(contentLength) > 0 && contentLength[0] == "0" {
-		return hosts.Host{}, fmt.Errorf("Unknown Host")
+		return hosts.Host{}, models.ErrHostNotFound
 	}
 
 	bodyBytes, err := ioutil.ReadAll(response.Body)
@@ -187,11 +219,13 @@ func (instance *OSctrlAPI) CheckHost(uuid string) (hosts.Host, error) {
 }
 
 func (instance *OSctrlAPI) ScheduleQuery(uuid string, query string) (string, error) {
-	if !instance.Authed {
-		err := instance.authenticate()
-		if err != nil {
-			return "", err
-		}
+	return instance.ScheduleQueryContext(context.Background(), uuid, query)
+}
+
+func (instance *OSctrlAPI) ScheduleQueryContext(ctx context.Context, uuid string, query string) (string, error) {
+	token, err := instance.ensureAuthenticated()
+	if err != nil {
+		return "", err
 	}
 	type QueryScheduleResponse struct {
 		QueryName string `json:"query_name"`
@@ -204,17 +238,20 @@ func (instance *OSctrlAPI) ScheduleQuery(uuid string, query string) (string, err
 	queryRequest := DistributedQueryRequest{UUIDs: []string{uuid}, Query: query}
 	qrJSON, _ := json.Marshal(queryRequest)
 
-	request, _ := http.NewRequest("POST", fmt.Sprintf("%s/api/v1/queries", instance.APIBase), bytes.NewReader(qrJSON))
-	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", instance.Token.Token))
+	request, _ := http.NewRequestWithContext(ctx, "POST", fmt.Sprintf("%s/api/v1/queries", instance.APIBase), bytes.NewReader(qrJSON))
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 	request.Header.Set("User-Agent", "goquery/1.0")
 	response, err := instance.Client.Do(request)
 
 	if err != nil {
-		instance.authenticate()
 		return "", fmt.Errorf("ScheduleQuery call failed: %s", err)
 	}
+	if response.StatusCode == 401 || response.StatusCode == 403 {
+		instance.invalidate()
+		return "", models.ErrUnauthenticated
+	}
 	if response.StatusCode == 404 {
-		return "", fmt.Errorf("Unknown Host")
+		return "", models.ErrHostNotFound
 	}
 	if response.StatusCode != 200 {
 		return "", fmt.Errorf("Server returned unknown error: %d", response.StatusCode)
@@ -227,14 +264,16 @@ func (instance *OSctrlAPI) ScheduleQuery(uuid string, query string) (string, err
 	qsResponse := QueryScheduleResponse{}
 	err = json.Unmarshal(bodyBytes, &qsResponse)
 	if err != nil {
-		instance.Authed = false
-		return "", err
+		return "", fmt.Errorf("Could not parse ScheduleQuery response: %s", err)
 	}
-	hosts.AddQueryToHost(uuid, hosts.Query{Name: qsResponse.QueryName, SQL: query})
 	return qsResponse.QueryName, nil
 }
 
-func (instance *OSctrlAPI) FetchResults(queryName string) ([]map[string]string, string, error) {
+func (instance *OSctrlAPI) FetchResults(queryName string) ([]map[string]string, models.QueryStatus, error) {
+	return instance.FetchResultsContext(context.Background(), queryName)
+}
+
+func (instance *OSctrlAPI) FetchResultsContext(ctx context.Context, queryName string) ([]map[string]string, models.QueryStatus, error) {
 	type ResultsResponse struct {
 		Rows   []map[string]string `json:"result"`
 		Status int                 `json:"status"`
@@ -243,52 +282,55 @@ func (instance *OSctrlAPI) FetchResults(queryName string) ([]map[string]string,
 
 	type MachineResults = map[string]ResultsResponse
 
-	if !instance.Authed {
-		err := instance.authenticate()
-		if err != nil {
-			return []map[string]string{}, "", err
-		}
+	unknown := models.QueryStatus{State: models.QueryUnknown}
+
+	token, err := instance.ensureAuthenticated()
+	if err != nil {
+		return []map[string]string{}, unknown, err
 	}
 
-	request, _ := http.NewRequest("GET", fmt.Sprintf("%s/api/v1/queries/results/%s", instance.APIBase, queryName), nil)
-	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", instance.Token.Token))
+	request, _ := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/api/v1/queries/results/%s", instance.APIBase, queryName), nil)
+	request.Header.Add("Authorization", fmt.Sprintf("Bearer %s", token))
 	request.Header.Set("User-Agent", "goquery/1.0")
 	response, err := instance.Client.Do(request)
 
 	if err != nil {
-		instance.authenticate()
-		return []map[string]string{}, "", fmt.Errorf("FetchResults call failed: %s", err)
+		return []map[string]string{}, unknown, fmt.Errorf("FetchResults call failed: %s", err)
+	}
+	if response.StatusCode == 401 || response.StatusCode == 403 {
+		instance.invalidate()
+		return []map[string]string{}, unknown, models.ErrUnauthenticated
 	}
 	if response.StatusCode == 404 {
-		return []map[string]string{}, "", fmt.Errorf("Unknown queryName")
+		return []map[string]string{}, unknown, models.ErrQueryNotFound
 	}
 	if response.StatusCode != 200 {
-		return []map[string]string{}, "", fmt.Errorf("Server returned unknown error: %d", response.StatusCode)
+		return []map[string]string{}, unknown, fmt.Errorf("Server returned unknown error: %d", response.StatusCode)
 	}
 
 	bodyBytes, err := ioutil.ReadAll(response.Body)
 	if err != nil {
-		return []map[string]string{}, "", fmt.Errorf("Could not read fetchResults response")
+		return []map[string]string{}, unknown, fmt.Errorf("Could not read fetchResults response")
 	}
 
 	apiResponse := MachineResults{}
 	if err := json.Unmarshal(bodyBytes, &apiResponse); err != nil {
-		instance.Authed = false
-		return []map[string]string{}, "", err
+		return []map[string]string{}, unknown, fmt.Errorf("Could not parse FetchResults response: %s", err)
 	}
 
 	if len(apiResponse) == 0 {
-		return []map[string]string{}, "Pending", nil
-	}
-
-	if len(apiResponse) < 1 {
-		return []map[string]string{}, "", fmt.Errorf("Got an unexpected number of results: %d", len(apiResponse))
+		return []map[string]string{}, models.QueryStatus{State: models.QueryPending}, nil
 	}
 
+	// Status is the osquery status code for the query, anything but 0 is a failure
 	for _, result := range apiResponse {
-		return result.Rows, string(result.Status), nil
+		if result.Status != 0 {
+			return result.Rows, models.QueryStatus{
+				State:   models.QueryFailed,
+				Message: fmt.Sprintf("osquery status code %d", result.Status),
+			}, nil
+		}
+		return result.Rows, models.QueryStatus{State: models.QueryComplete}, nil
 	}
-
-	panic("Machine results was guaranteed to have one result yet we didn't return it")
-	return []map[string]string{}, "", fmt.Errorf("FetchResults call failed: %s", err)
+	return []map[string]string{}, unknown, nil
 }
@@ -0,0 +1,14 @@
+package osctrl
+
+import (
+	"encoding/json"
+
+	"github.com/AbGuthrie/goquery/v2/api"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func init() {
+	api.Register("osctrl", func(settings json.RawMessage, debug bool) (models.GoQueryAPI, error) {
+		return CreateOSctrlAPI(debug)
+	})
+}
@@ -0,0 +1,30 @@
+package tlsserver
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AbGuthrie/goquery/v2/api"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// driverConfig is the tlsserver block in the drivers section of the goquery config
+type driverConfig struct {
+	serverConfig
+	// TLSServerCfgPath is the older way of configuring the driver, a server config file
+	// read instead of the settings in the block
+	TLSServerCfgPath string `json:"tlsServerCfgPath"`
+}
+
+func init() {
+	api.Register("tlsserver", func(settings json.RawMessage, debug bool) (models.GoQueryAPI, error) {
+		driverCfg := driverConfig{}
+		if err := json.Unmarshal(settings, &driverCfg); err != nil {
+			return nil, fmt.Errorf("Error parsing tlsserver driver config: %s", err)
+		}
+		if driverCfg.TLSServerCfgPath != "" {
+			return CreateTLSServerAPI(driverCfg.TLSServerCfgPath, debug)
+		}
+		return newTLSServerAPI(driverCfg.serverConfig, debug)
+	})
+}
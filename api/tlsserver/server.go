@@ -0,0 +1,554 @@
+// Package tlsserver is a goquery API that is its own osquery TLS server. osqueryd agents
+// enroll directly with the goquery process and queries are handed to them over the
+// distributed read and write endpoints, so no separate backend is needed.
+package tlsserver
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/subtle"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/models"
+
+	id "github.com/google/uuid"
+)
+
+// DefaultListenAddress is used when the server config doesn't set listenAddress
+const DefaultListenAddress = ":8001"
+
+// maxRequestBytes bounds the body of a request from an agent, mostly query results
+const maxRequestBytes = 32 * 1024 * 1024
+
+// accelerateSeconds asks agents with queries waiting to check in faster for a while
+const accelerateSeconds = 300
+
+// resultRetention is how long the results of a finished query can still be fetched, so
+// .resume works for a while, before they are forgotten
+const resultRetention = 10 * time.Minute
+
+// serverConfig is the listen address, certificate and enroll secret, read from a file or
+// the tlsserver driver block
+type serverConfig struct {
+	ListenAddress    string `json:"listenAddress"`
+	Certificate      string `json:"certificate"`
+	Key              string `json:"key"`
+	EnrollSecret     string `json:"enrollSecret"`
+	EnrollSecretPath string `json:"enrollSecretPath"`
+	LogPath          string `json:"logPath"`
+}
+
+// enrolledHost is a host that enrolled with the server, keyed by its host identifier
+type enrolledHost struct {
+	identifier string
+	nodeKey    string
+	host       hosts.Host
+	lastSeen   time.Time
+	// waiting holds the names of queries not yet handed to the host, oldest first
+	waiting []string
+	// inFlight holds the names of queries handed to the host and not yet answered
+	inFlight map[string]bool
+}
+
+// distributedQuery is a query scheduled on a host, keyed by the name goquery knows it by
+type distributedQuery struct {
+	sql      string
+	done     bool
+	finished time.Time
+	rows     []map[string]string
+	message  string
+}
+
+type TLSServerAPI struct {
+	Server *http.Server
+	debug  bool
+	secret []byte
+	// logger records what agents do, it never writes over the goquery prompt unless
+	// debug is set without a logPath
+	logger  *log.Logger
+	logFile *os.File
+
+	mutex sync.Mutex
+	// nodes maps node keys to hosts, and hosts maps host identifiers to hosts
+	nodes   map[string]*enrolledHost
+	hosts   map[string]*enrolledHost
+	queries map[string]*distributedQuery
+}
+
+// CreateTLSServerAPI creates and returns an api implementation that implements the models.GoQueryAPI
+// interface by serving the osquery TLS API configured by the server config at configPath.
+// The server is listening when this returns. Refused enrollments are logged to the
+// logPath file when one is set, and with debug set every agent request is logged too,
+// to standard error if there is no logPath.
+func CreateTLSServerAPI(configPath string, debug bool) (models.GoQueryAPI, error) {
+	configBytes, err := ioutil.ReadFile(strings.TrimSpace(configPath))
+	if err != nil {
+		return nil, fmt.Errorf("Error reading TLS server config file: %s", err)
+	}
+	serverCfg := serverConfig{}
+	if err := json.Unmarshal(configBytes, &serverCfg); err != nil {
+		return nil, fmt.Errorf("Error parsing TLS server config file: %s", err)
+	}
+	return newTLSServerAPI(serverCfg, debug)
+}
+
+// newTLSServerAPI starts the server once its config is read, from a file or the driver's
+// config block
+func newTLSServerAPI(serverCfg serverConfig, debug bool) (models.GoQueryAPI, error) {
+	if serverCfg.ListenAddress == "" {
+		serverCfg.ListenAddress = DefaultListenAddress
+	}
+
+	secret := serverCfg.EnrollSecret
+	if serverCfg.EnrollSecretPath != "" {
+		secretBytes, err := ioutil.ReadFile(serverCfg.EnrollSecretPath)
+		if err != nil {
+			return nil, fmt.Errorf("Error reading enroll secret: %s", err)
+		}
+		// osquery trims the secret it reads from --enroll_secret_path too
+		secret = strings.TrimSpace(string(secretBytes))
+	}
+	if secret == "" {
+		return nil, fmt.Errorf("TLS server config must set enrollSecret or enrollSecretPath")
+	}
+
+	certificate, err := tls.LoadX509KeyPair(serverCfg.Certificate, serverCfg.Key)
+	if err != nil {
+		return nil, fmt.Errorf("Error loading TLS server certificate: %s", err)
+	}
+	logger := log.New(ioutil.Discard, "", 0)
+	var logFile *os.File
+	if serverCfg.LogPath != "" {
+		logFile, err = os.OpenFile(serverCfg.LogPath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+		if err != nil {
+			return nil, fmt.Errorf("Error opening TLS server log file: %s", err)
+		}
+		logger = log.New(logFile, "", log.LstdFlags)
+	} else if debug {
+		logger = log.New(os.Stderr, "", log.LstdFlags)
+	}
+	listener, err := net.Listen("tcp", serverCfg.ListenAddress)
+	if err != nil {
+		if logFile != nil {
+			logFile.Close()
+		}
+		return nil, fmt.Errorf("Could not listen on %s: %s", serverCfg.ListenAddress, err)
+	}
+
+	instance := &TLSServerAPI{
+		debug:   debug,
+		secret:  []byte(secret),
+		logger:  logger,
+		logFile: logFile,
+		nodes:   make(map[string]*enrolledHost),
+		hosts:   make(map[string]*enrolledHost),
+		queries: make(map[string]*distributedQuery),
+	}
+	mux := http.NewServeMux()
+	// The same endpoints as goserver so agents configured for it work unchanged
+	mux.HandleFunc("/enroll", instance.enroll)
+	mux.HandleFunc("/config", instance.config)
+	mux.HandleFunc("/log", instance.log)
+	mux.HandleFunc("/distributedRead", instance.distributedRead)
+	mux.HandleFunc("/distributedWrite", instance.distributedWrite)
+	instance.Server = &http.Server{
+		Handler: mux,
+		TLSConfig: &tls.Config{
+			Certificates: []tls.Certificate{certificate},
+			MinVersion:   tls.VersionTLS12,
+		},
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	// Failed handshakes and dropped connections are only worth logging when debugging
+	instance.Server.ErrorLog = log.New(ioutil.Discard, "", 0)
+	if debug {
+		instance.Server.ErrorLog = logger
+	}
+
+	// This is printed before the prompt starts, everything after goes to the logger
+	fmt.Printf("Listening for osquery hosts on %s\n", listener.Addr())
+	go func() {
+		if err := instance.Server.ServeTLS(listener, "", ""); err != nil && err != http.ErrServerClosed {
+			instance.logger.Printf("osquery TLS server stopped: %s", err)
+		}
+	}()
+	return instance, nil
+}
+
+// Close stops the server, enrolled hosts and queries are forgotten
+func (instance *TLSServerAPI) Close() error {
+	err := instance.Server.Close()
+	if instance.logFile != nil {
+		instance.logFile.Close()
+	}
+	return err
+}
+
+// debugf logs agent requests when debug is set
+func (instance *TLSServerAPI) debugf(format string, args ...interface{}) {
+	if instance.debug {
+		instance.logger.Printf(format, args...)
+	}
+}
+
+// newNodeKey returns a random key for an agent to authenticate with once enrolled
+func newNodeKey() (string, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(key), nil
+}
+
+// readRequest decodes the JSON body of an agent request into body
+func readRequest(w http.ResponseWriter, r *http.Request, body interface{}) bool {
+	if r.Method != "POST" {
+		w.WriteHeader(http.StatusMethodNotAllowed)
+		return false
+	}
+	bodyBytes, err := ioutil.ReadAll(http.MaxBytesReader(w, r.Body, maxRequestBytes))
+	if err != nil {
+		w.WriteHeader(http.StatusRequestEntityTooLarge)
+		return false
+	}
+	if err := json.Unmarshal(bodyBytes, body); err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return false
+	}
+	return true
+}
+
+func writeResponse(w http.ResponseWriter, body interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(body)
+}
+
+// nodeInvalid tells an agent to enroll again
+func nodeInvalid(w http.ResponseWriter) {
+	writeResponse(w, map[string]bool{"node_invalid": true})
+}
+
+// node returns the host with nodeKey and marks it as seen, the caller must hold the mutex
+func (instance *TLSServerAPI) node(nodeKey string) (*enrolledHost, bool) {
+	host, ok := instance.nodes[nodeKey]
+	if ok {
+		host.lastSeen = time.Now()
+	}
+	return host, ok
+}
+
+// Begin osquery API endpoints
+
+func (instance *TLSServerAPI) enroll(w http.ResponseWriter, r *http.Request) {
+	type enrollBody struct {
+		EnrollSecret   string `json:"enroll_secret"`
+		HostIdentifier string `json:"host_identifier"`
+		HostDetails    struct {
+			SystemInfo struct {
+				UUID         string `json:"uuid"`
+				ComputerName string `json:"computer_name"`
+				Hostname     string `json:"hostname"`
+			} `json:"system_info"`
+			OsqueryInfo struct {
+				Version string `json:"version"`
+			} `json:"osquery_info"`
+			OsVersion struct {
+				Platform string `json:"platform"`
+				Version  string `json:"version"`
+			} `json:"os_version"`
+		} `json:"host_details"`
+	}
+
+	body := enrollBody{}
+	if !readRequest(w, r, &body) {
+		return
+	}
+	if subtle.ConstantTimeCompare([]byte(body.EnrollSecret), instance.secret) != 1 {
+		instance.logger.Printf("Refused enrollment from %s: incorrect enroll secret", r.RemoteAddr)
+		nodeInvalid(w)
+		return
+	}
+	details := body.HostDetails
+	identifier := body.HostIdentifier
+	if identifier == "" {
+		identifier = details.SystemInfo.UUID
+	}
+	if identifier == "" {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	nodeKey, err := newNodeKey()
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	computerName := details.SystemInfo.ComputerName
+	if computerName == "" {
+		computerName = details.SystemInfo.Hostname
+	}
+	host := &enrolledHost{
+		identifier: identifier,
+		nodeKey:    nodeKey,
+		lastSeen:   time.Now(),
+		inFlight:   make(map[string]bool),
+		host: hosts.Host{
+			UUID:             identifier,
+			ComputerName:     computerName,
+			Platform:         fmt.Sprintf("%s (%s)", details.OsVersion.Platform, details.OsVersion.Version),
+			Version:          details.OsqueryInfo.Version,
+			CurrentDirectory: "/",
+		},
+	}
+
+	instance.mutex.Lock()
+	// A host enrolling again keeps the queries it hasn't been handed yet. The ones it was
+	// handed most likely died with the osqueryd that enrolled before, and could be what
+	// crashed it, so they fail rather than being handed out again.
+	if previous, ok := instance.hosts[identifier]; ok {
+		delete(instance.nodes, previous.nodeKey)
+		host.waiting = previous.waiting
+		for name := range previous.inFlight {
+			if query, ok := instance.queries[name]; ok && !query.done {
+				instance.finishLocked(query, nil, "The host enrolled again before returning results")
+			}
+		}
+	}
+	instance.hosts[identifier] = host
+	instance.nodes[nodeKey] = host
+	instance.mutex.Unlock()
+
+	instance.debugf("Enrolled %s (%s) from %s", identifier, computerName, r.RemoteAddr)
+	writeResponse(w, map[string]interface{}{"node_key": nodeKey, "node_invalid": false})
+}
+
+func (instance *TLSServerAPI) config(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		NodeKey string `json:"node_key"`
+	}{}
+	if !readRequest(w, r, &body) {
+		return
+	}
+	instance.mutex.Lock()
+	_, ok := instance.node(body.NodeKey)
+	instance.mutex.Unlock()
+	if !ok {
+		nodeInvalid(w)
+		return
+	}
+	// Hosts are only used for distributed queries so they get an empty schedule
+	writeResponse(w, map[string]interface{}{"schedule": map[string]interface{}{}, "node_invalid": false})
+}
+
+func (instance *TLSServerAPI) log(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		NodeKey string `json:"node_key"`
+	}{}
+	if !readRequest(w, r, &body) {
+		return
+	}
+	instance.mutex.Lock()
+	_, ok := instance.node(body.NodeKey)
+	instance.mutex.Unlock()
+	if !ok {
+		nodeInvalid(w)
+		return
+	}
+	// There is no schedule so the logs are only status logs, which are dropped
+	writeResponse(w, map[string]bool{"node_invalid": false})
+}
+
+func (instance *TLSServerAPI) distributedRead(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		NodeKey string `json:"node_key"`
+	}{}
+	if !readRequest(w, r, &body) {
+		return
+	}
+
+	instance.mutex.Lock()
+	host, ok := instance.node(body.NodeKey)
+	if !ok {
+		instance.mutex.Unlock()
+		nodeInvalid(w)
+		return
+	}
+	// Each query is handed out once, one the host never answers stays pending until
+	// goquery gives up on it
+	queries := make(map[string]string, len(host.waiting))
+	for _, name := range host.waiting {
+		queries[name] = instance.queries[name].sql
+		host.inFlight[name] = true
+	}
+	host.waiting = nil
+	instance.mutex.Unlock()
+
+	response := map[string]interface{}{"queries": queries, "node_invalid": false}
+	if len(queries) > 0 {
+		instance.debugf("Sent %d queries to %s", len(queries), host.identifier)
+		response["accelerate"] = accelerateSeconds
+	}
+	writeResponse(w, response)
+}
+
+func (instance *TLSServerAPI) distributedWrite(w http.ResponseWriter, r *http.Request) {
+	body := struct {
+		NodeKey  string                     `json:"node_key"`
+		Queries  map[string]json.RawMessage `json:"queries"`
+		Statuses map[string]int             `json:"statuses"`
+		Messages map[string]string          `json:"messages"`
+	}{}
+	if !readRequest(w, r, &body) {
+		return
+	}
+
+	instance.mutex.Lock()
+	defer instance.mutex.Unlock()
+	host, ok := instance.node(body.NodeKey)
+	if !ok {
+		nodeInvalid(w)
+		return
+	}
+
+	names := make(map[string]bool)
+	for name := range body.Queries {
+		names[name] = true
+	}
+	for name := range body.Statuses {
+		names[name] = true
+	}
+	for name := range names {
+		delete(host.inFlight, name)
+		query, ok := instance.queries[name]
+		if !ok || query.done {
+			instance.debugf("Dropped results from %s for unknown query %s", host.identifier, name)
+			continue
+		}
+		// Failed queries come back as an empty string rather than a list of rows
+		rows := []map[string]string{}
+		if len(body.Queries[name]) > 0 && json.Unmarshal(body.Queries[name], &rows) != nil {
+			rows = []map[string]string{}
+		}
+		message := ""
+		if status := body.Statuses[name]; status != 0 {
+			// osquery sends the error for a failed query in messages when it has one
+			message = body.Messages[name]
+			if message == "" {
+				message = fmt.Sprintf("Status Code %d", status)
+			}
+		}
+		instance.finishLocked(query, rows, message)
+		instance.debugf("Received results for %s from %s", name, host.identifier)
+	}
+	writeResponse(w, map[string]bool{"node_invalid": false})
+}
+
+// End osquery API endpoints
+
+// finishLocked records the outcome of a query, the caller must hold the mutex
+func (instance *TLSServerAPI) finishLocked(query *distributedQuery, rows []map[string]string, message string) {
+	query.done = true
+	query.finished = time.Now()
+	query.rows = rows
+	query.message = message
+}
+
+// evictLocked forgets queries that finished more than resultRetention ago, the caller
+// must hold the mutex
+func (instance *TLSServerAPI) evictLocked() {
+	for queryName, query := range instance.queries {
+		if query.done && time.Since(query.finished) > resultRetention {
+			delete(instance.queries, queryName)
+		}
+	}
+}
+
+// findHost looks a host up by its host identifier, system UUID or computer name, the
+// caller must hold the mutex
+func (instance *TLSServerAPI) findHost(uuid string) (*enrolledHost, bool) {
+	if host, ok := instance.hosts[uuid]; ok {
+		return host, true
+	}
+	var found *enrolledHost
+	for _, host := range instance.hosts {
+		if !strings.EqualFold(host.host.ComputerName, uuid) {
+			continue
+		}
+		// Prefer whichever host checked in last if a name was reused
+		if found == nil || host.lastSeen.After(found.lastSeen) {
+			found = host
+		}
+	}
+	return found, found != nil
+}
+
+func (instance *TLSServerAPI) CheckHost(uuid string) (hosts.Host, error) {
+	return instance.CheckHostContext(context.Background(), uuid)
+}
+
+// CheckHostContext looks an enrolled host up by its host identifier or computer name
+func (instance *TLSServerAPI) CheckHostContext(ctx context.Context, uuid string) (hosts.Host, error) {
+	instance.mutex.Lock()
+	defer instance.mutex.Unlock()
+	host, ok := instance.findHost(uuid)
+	if !ok {
+		return hosts.Host{}, fmt.Errorf("CheckHost call failed: %w", models.ErrHostNotFound)
+	}
+	return host.host, nil
+}
+
+func (instance *TLSServerAPI) ScheduleQuery(uuid string, query string) (string, error) {
+	return instance.ScheduleQueryContext(context.Background(), uuid, query)
+}
+
+// ScheduleQueryContext queues the query for the host to pick up on its next distributed read
+func (instance *TLSServerAPI) ScheduleQueryContext(ctx context.Context, uuid string, query string) (string, error) {
+	instance.mutex.Lock()
+	defer instance.mutex.Unlock()
+	host, ok := instance.findHost(uuid)
+	if !ok {
+		return "", fmt.Errorf("ScheduleQuery call failed: %w", models.ErrHostNotFound)
+	}
+
+	instance.evictLocked()
+	queryName := id.New().String()
+	instance.queries[queryName] = &distributedQuery{sql: query}
+	host.waiting = append(host.waiting, queryName)
+	return queryName, nil
+}
+
+func (instance *TLSServerAPI) FetchResults(queryName string) ([]map[string]string, models.QueryStatus, error) {
+	return instance.FetchResultsContext(context.Background(), queryName)
+}
+
+// FetchResultsContext reports on a query from the in-memory queue, it is pending until
+// the host writes its results
+func (instance *TLSServerAPI) FetchResultsContext(ctx context.Context, queryName string) ([]map[string]string, models.QueryStatus, error) {
+	instance.mutex.Lock()
+	defer instance.mutex.Unlock()
+
+	instance.evictLocked()
+	query, ok := instance.queries[queryName]
+	if !ok {
+		return []map[string]string{}, models.QueryStatus{State: models.QueryUnknown}, models.ErrQueryNotFound
+	}
+	if !query.done {
+		return []map[string]string{}, models.QueryStatus{State: models.QueryPending}, nil
+	}
+	if query.message != "" {
+		return []map[string]string{}, models.QueryStatus{State: models.QueryFailed, Message: query.message}, nil
+	}
+	return query.rows, models.QueryStatus{State: models.QueryComplete}, nil
+}
@@ -0,0 +1,136 @@
+package tlsserver
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/api"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func newTestServer() *TLSServerAPI {
+	return &TLSServerAPI{
+		secret:  []byte("secret"),
+		logger:  log.New(ioutil.Discard, "", 0),
+		nodes:   make(map[string]*enrolledHost),
+		hosts:   make(map[string]*enrolledHost),
+		queries: make(map[string]*distributedQuery),
+	}
+}
+
+// post sends an agent request to handler and decodes its response
+func post(t *testing.T, handler http.HandlerFunc, body interface{}) map[string]interface{} {
+	encoded, _ := json.Marshal(body)
+	recorder := httptest.NewRecorder()
+	handler(recorder, httptest.NewRequest("POST", "/", bytes.NewReader(encoded)))
+	response := map[string]interface{}{}
+	if err := json.Unmarshal(recorder.Body.Bytes(), &response); err != nil {
+		t.Fatalf("undecodable response %q: %s", recorder.Body, err)
+	}
+	return response
+}
+
+func enroll(t *testing.T, instance *TLSServerAPI) string {
+	response := post(t, instance.enroll, map[string]interface{}{"enroll_secret": "secret", "host_identifier": "host-1"})
+	nodeKey, _ := response["node_key"].(string)
+	if nodeKey == "" {
+		t.Fatalf("enrollment failed: %v", response)
+	}
+	return nodeKey
+}
+
+func TestDriverReadsTheServerConfigFromItsBlock(t *testing.T) {
+	tests := []struct {
+		settings string
+		err      string
+	}{
+		{`{}`, "must set enrollSecret or enrollSecretPath"},
+		{`{"enrollSecret": "secret", "certificate": "/nonexistent/server.crt", "key": "/nonexistent/server.key"}`, "Error loading TLS server certificate"},
+		{`{"tlsServerCfgPath": "/nonexistent/tlsserver.json"}`, "Error reading TLS server config file"},
+	}
+	for _, test := range tests {
+		_, err := api.Create("tlsserver", json.RawMessage(test.settings), false)
+		if err == nil || !strings.Contains(err.Error(), test.err) {
+			t.Errorf("Create(%s) = %v, want %q", test.settings, err, test.err)
+		}
+	}
+}
+
+func TestDistributedQueryRoundTrip(t *testing.T) {
+	instance := newTestServer()
+	if response := post(t, instance.enroll, map[string]string{"enroll_secret": "wrong", "host_identifier": "host-1"}); response["node_invalid"] != true {
+		t.Fatalf("enrolled with the wrong secret: %v", response)
+	}
+	nodeKey := enroll(t, instance)
+
+	queryName, err := instance.ScheduleQuery("host-1", "select 1 as one")
+	if err != nil {
+		t.Fatal(err)
+	}
+	read := post(t, instance.distributedRead, map[string]string{"node_key": nodeKey})
+	if queries, _ := read["queries"].(map[string]interface{}); queries[queryName] != "select 1 as one" {
+		t.Fatalf("distributedRead() = %v", read)
+	}
+	if _, status, _ := instance.FetchResults(queryName); status.State != models.QueryPending {
+		t.Fatalf("an unanswered query is %s", status.State)
+	}
+	post(t, instance.distributedWrite, map[string]interface{}{
+		"node_key": nodeKey,
+		"queries":  map[string]interface{}{queryName: []map[string]string{{"one": "1"}}},
+		"statuses": map[string]int{queryName: 0},
+	})
+	rows, status, err := instance.FetchResults(queryName)
+	if err != nil || status.State != models.QueryComplete || len(rows) != 1 || rows[0]["one"] != "1" {
+		t.Fatalf("FetchResults() = %v, %+v, %v", rows, status, err)
+	}
+}
+
+func TestEnrollingAgainFailsQueriesInFlight(t *testing.T) {
+	instance := newTestServer()
+	nodeKey := enroll(t, instance)
+	handedOut, _ := instance.ScheduleQuery("host-1", "select * from crashes")
+	post(t, instance.distributedRead, map[string]string{"node_key": nodeKey})
+	waiting, _ := instance.ScheduleQuery("host-1", "select 1")
+
+	nodeKey = enroll(t, instance)
+	if _, status, _ := instance.FetchResults(handedOut); status.State != models.QueryFailed {
+		t.Fatalf("a query handed to the previous enrollment is %s, want Failed", status.State)
+	}
+	read := post(t, instance.distributedRead, map[string]string{"node_key": nodeKey})
+	queries, _ := read["queries"].(map[string]interface{})
+	if len(queries) != 1 || queries[waiting] != "select 1" {
+		t.Fatalf("the new enrollment should be handed only the waiting query, got %v", queries)
+	}
+}
+
+func TestFinishedQueriesAreEvicted(t *testing.T) {
+	instance := newTestServer()
+	nodeKey := enroll(t, instance)
+	queryName, _ := instance.ScheduleQuery("host-1", "select 1")
+	post(t, instance.distributedRead, map[string]string{"node_key": nodeKey})
+	post(t, instance.distributedWrite, map[string]interface{}{
+		"node_key": nodeKey,
+		"queries":  map[string]interface{}{queryName: ""},
+		"statuses": map[string]int{queryName: 1},
+		"messages": map[string]string{queryName: "no such table: missing"},
+	})
+	if _, status, _ := instance.FetchResults(queryName); status.State != models.QueryFailed || status.Message != "no such table: missing" {
+		t.Fatalf("FetchResults() status = %+v", status)
+	}
+
+	instance.queries[queryName].finished = time.Now().Add(-resultRetention - time.Second)
+	if _, _, err := instance.FetchResults(queryName); !errors.Is(err, models.ErrQueryNotFound) {
+		t.Fatalf("FetchResults() of an evicted query = %v, want ErrQueryNotFound", err)
+	}
+	if len(instance.queries) != 0 {
+		t.Fatalf("%d queries left after eviction", len(instance.queries))
+	}
+}
@@ -2,13 +2,14 @@ package uptycs
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
-	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
 	"net/http/httputil"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/AbGuthrie/goquery/v2/config"
@@ -17,7 +18,6 @@ import (
 	"github.com/dgrijalva/jwt-go"
 	id "github.com/google/uuid"
 	"github.com/tidwall/gjson"
-	"golang.org/x/net/context"
 )
 
 type uptycsConfig struct {
@@ -48,6 +48,8 @@ type UptycsAPI struct {
 var (
 	scheduleQueryTemplate = `{"type":"realtime","query":"%s","filtering":{"filters":{"id":{"equals":"%s"}}}}`
 	db                    map[string]string
+	// dbMutex guards db, queries fanned out to many hosts are scheduled concurrently
+	dbMutex sync.Mutex
 )
 
 /*
@@ -91,11 +93,17 @@ func debugHTTPResponse(resp *http.Response) {
 
 // CreateUptycsAPI creates an authenticated instance of the `UptycsAPI` object
 func CreateUptycsAPI(uptCfgPath string, debugEnabled bool) (models.GoQueryAPI, error) {
-	retVal := UptycsAPI{DebugMode: debugEnabled}
 	uptCfg, err := loadCredentials(uptCfgPath)
 	if err != nil {
-		return retVal, err
+		return UptycsAPI{DebugMode: debugEnabled}, err
 	}
+	return newUptycsAPI(uptCfg, debugEnabled)
+}
+
+// newUptycsAPI authenticates with credentials read from a file or the driver's config block
+func newUptycsAPI(uptCfg *uptycsConfig, debugEnabled bool) (models.GoQueryAPI, error) {
+	retVal := UptycsAPI{DebugMode: debugEnabled}
+	var err error
 	retVal.httpClient = &http.Client{
 		Timeout: time.Second * 10,
 	}
@@ -125,7 +133,9 @@ func CreateUptycsAPI(uptCfgPath string, debugEnabled bool) (models.GoQueryAPI, e
 		fmt.Sprintf("Bearer %s", token),
 	)
 	retVal.Authed = true
+	dbMutex.Lock()
 	db = make(map[string]string)
+	dbMutex.Unlock()
 	return retVal, nil
 }
 
@@ -139,7 +149,7 @@ func (u UptycsAPI) doHTTPReq(req *http.Request) (string, error) {
 	}
 	resp, err := u.httpClient.Do(req)
 	if err != nil {
-		return "", nil
+		return "", err
 	}
 	if u.DebugMode {
 		debugHTTPResponse(resp)
@@ -148,16 +158,16 @@ func (u UptycsAPI) doHTTPReq(req *http.Request) (string, error) {
 	return string(respData), err
 }
 
-func (u UptycsAPI) getAssetInfo(uuid string) (string, error) {
-	req := u.defaultReqObj.Clone(context.TODO())
+func (u UptycsAPI) getAssetInfo(ctx context.Context, uuid string) (string, error) {
+	req := u.defaultReqObj.Clone(ctx)
 	req.URL.Path = fmt.Sprintf(
 		"%s/assets/%s", req.URL.Path, uuid,
 	)
 	return u.doHTTPReq(req)
 }
 
-func (u UptycsAPI) getUsers(uuid string) (string, error) {
-	req := u.defaultReqObj.Clone(context.TODO())
+func (u UptycsAPI) getUsers(ctx context.Context, uuid string) (string, error) {
+	req := u.defaultReqObj.Clone(ctx)
 	req.URL.Path = fmt.Sprintf(
 		"%s/assets/%s/user", req.URL.Path, uuid,
 	)
@@ -165,14 +175,18 @@ func (u UptycsAPI) getUsers(uuid string) (string, error) {
 }
 
 func (u UptycsAPI) CheckHost(uuid string) (hosts.Host, error) {
+	return u.CheckHostContext(context.Background(), uuid)
+}
+
+func (u UptycsAPI) CheckHostContext(ctx context.Context, uuid string) (hosts.Host, error) {
 	retVal := hosts.Host{
 		UUID:             uuid,
 		CurrentDirectory: "/",
 	}
 	if !u.Authed {
-		return retVal, errors.New("Error, UptycsAPI object is not yet initialized")
+		return retVal, fmt.Errorf("%w: UptycsAPI object is not yet initialized", models.ErrUnauthenticated)
 	}
-	queryResult, err := u.getAssetInfo(uuid)
+	queryResult, err := u.getAssetInfo(ctx, uuid)
 	if err != nil {
 		return retVal, err
 	}
@@ -188,7 +202,7 @@ func (u UptycsAPI) CheckHost(uuid string) (hosts.Host, error) {
 		gjson.Get(queryResult, "os_key").String(),
 	)
 	retVal.Version = gjson.Get(queryResult, "osqueryVersion").String()
-	queryResult, err = u.getUsers(uuid)
+	queryResult, err = u.getUsers(ctx, uuid)
 	if err != nil {
 		return retVal, err
 	}
@@ -211,12 +225,16 @@ func (u UptycsAPI) CheckHost(uuid string) (hosts.Host, error) {
 	as a key, then return that as the "result".
 */
 func (u UptycsAPI) ScheduleQuery(uuid string, query string) (string, error) {
+	return u.ScheduleQueryContext(context.Background(), uuid, query)
+}
+
+func (u UptycsAPI) ScheduleQueryContext(ctx context.Context, uuid string, query string) (string, error) {
 	if !u.Authed {
-		return "", errors.New("Error, UptycsAPI object is not yet initialized")
+		return "", fmt.Errorf("%w: UptycsAPI object is not yet initialized", models.ErrUnauthenticated)
 	}
 	query = strings.ReplaceAll(query, `"`, `\"`)
 	finalBodyString := fmt.Sprintf(scheduleQueryTemplate, query, uuid)
-	req := u.defaultReqObj.Clone(context.TODO())
+	req := u.defaultReqObj.Clone(ctx)
 	req.URL.Path = fmt.Sprintf("%s/assets/query", req.URL.Path)
 	req.Method = "POST"
 	req.Body = ioutil.NopCloser(bytes.NewBufferString(finalBodyString))
@@ -228,19 +246,31 @@ func (u UptycsAPI) ScheduleQuery(uuid string, query string) (string, error) {
 		return "", fmt.Errorf("Error making scheduled query: %s", err)
 	}
 	queryUUID := id.New().String()
+	dbMutex.Lock()
 	db[queryUUID] = result
-	hosts.AddQueryToHost(uuid, hosts.Query{
-		Name: queryUUID,
-		SQL:  query,
-	})
+	dbMutex.Unlock()
 	return queryUUID, nil
 }
 
-func (u UptycsAPI) FetchResults(queryName string) ([]map[string]string, string, error) {
+func (u UptycsAPI) FetchResults(queryName string) ([]map[string]string, models.QueryStatus, error) {
+	return u.FetchResultsContext(context.Background(), queryName)
+}
+
+// FetchResultsContext only reads results stored by ScheduleQueryContext so no request is made.
+// Realtime queries have always finished by then, so the status is either complete or failed.
+func (u UptycsAPI) FetchResultsContext(ctx context.Context, queryName string) ([]map[string]string, models.QueryStatus, error) {
 	retVal := []map[string]string{}
+	if err := ctx.Err(); err != nil {
+		return retVal, models.QueryStatus{State: models.QueryUnknown}, err
+	}
+	dbMutex.Lock()
 	queryResult, ok := db[queryName]
+	dbMutex.Unlock()
 	if !ok {
-		return retVal, "", errors.New("Query not found")
+		return retVal, models.QueryStatus{State: models.QueryUnknown}, models.ErrQueryNotFound
+	}
+	if errMsg := gjson.Get(queryResult, "error").String(); len(errMsg) > 0 {
+		return retVal, models.QueryStatus{State: models.QueryFailed, Message: errMsg}, nil
 	}
 	for _, item := range gjson.Get(queryResult, "items").Array() {
 		row := make(map[string]string)
@@ -249,5 +279,5 @@ func (u UptycsAPI) FetchResults(queryName string) ([]map[string]string, string,
 		}
 		retVal = append(retVal, row)
 	}
-	return retVal, "", nil
+	return retVal, models.QueryStatus{State: models.QueryComplete}, nil
 }
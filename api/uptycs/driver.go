@@ -0,0 +1,30 @@
+package uptycs
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/AbGuthrie/goquery/v2/api"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// driverConfig is the uptycs block in the drivers section of the goquery config
+type driverConfig struct {
+	uptycsConfig
+	// UptCfgPath is the older way of configuring the driver, a credentials file read
+	// instead of the credentials in the block
+	UptCfgPath string `json:"uptCfgPath"`
+}
+
+func init() {
+	api.Register("uptycs", func(settings json.RawMessage, debug bool) (models.GoQueryAPI, error) {
+		driverCfg := driverConfig{}
+		if err := json.Unmarshal(settings, &driverCfg); err != nil {
+			return nil, fmt.Errorf("Error parsing uptycs driver config: %s", err)
+		}
+		if driverCfg.UptCfgPath != "" {
+			return CreateUptycsAPI(driverCfg.UptCfgPath, debug)
+		}
+		return newUptycsAPI(&driverCfg.uptycsConfig, debug)
+	})
+}
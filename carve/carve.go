@@ -0,0 +1,133 @@
+// Package carve pulls files from hosts through an API that implements models.FileCarver.
+// A carve is uploaded by osquery as a tar archive split into blocks, which are fetched,
+// reassembled and checked here before the carved file is extracted.
+package carve
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+const pollInterval = time.Second
+
+// MaxCarveSize is the largest carve archive Download will fetch, the whole archive is held
+// in memory while it is reassembled
+const MaxCarveSize = 1 << 30
+
+// Download carves path on the host with uuid, waits for every block to be uploaded and
+// returns the reassembled archive. progress is called, if set, whenever the upload moves.
+func Download(ctx context.Context, carver models.FileCarver, uuid, path string, progress func(models.CarveStatus)) ([]byte, error) {
+	carveID, err := carver.StartCarve(ctx, uuid, path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not start carve: %s", err)
+	}
+
+	status, err := waitForUpload(ctx, carver, carveID, progress)
+	if err != nil {
+		return nil, err
+	}
+
+	if status.CarveSize < 0 || status.CarveSize > MaxCarveSize {
+		return nil, fmt.Errorf("Carve is %d bytes, more than the %d bytes goquery will download", status.CarveSize, int64(MaxCarveSize))
+	}
+	archive := bytes.NewBuffer(make([]byte, 0, status.CarveSize))
+	for blockID := 0; blockID < status.BlockCount; blockID++ {
+		block, err := carver.FetchCarveBlock(ctx, carveID, blockID)
+		if err != nil {
+			return nil, fmt.Errorf("Could not fetch carve block %d: %s", blockID, err)
+		}
+		// Stop as soon as the blocks outgrow the reported size instead of buffering them all
+		if int64(archive.Len()+len(block)) > status.CarveSize {
+			return nil, fmt.Errorf("Carve blocks are larger than the %d bytes the host reported", status.CarveSize)
+		}
+		archive.Write(block)
+	}
+
+	if int64(archive.Len()) != status.CarveSize {
+		return nil, fmt.Errorf("Carve is %d bytes but the host reported %d", archive.Len(), status.CarveSize)
+	}
+	return archive.Bytes(), nil
+}
+
+func waitForUpload(ctx context.Context, carver models.FileCarver, carveID string, progress func(models.CarveStatus)) (models.CarveStatus, error) {
+	lastReceived := -1
+	for {
+		status, err := carver.CarveStatus(ctx, carveID)
+		if err != nil {
+			return status, err
+		}
+		if status.Error != "" {
+			return status, fmt.Errorf("Carve failed: %s", status.Error)
+		}
+		if progress != nil && status.Started && status.BlocksReceived != lastReceived {
+			lastReceived = status.BlocksReceived
+			progress(status)
+		}
+		if status.Complete() {
+			return status, nil
+		}
+		select {
+		case <-ctx.Done():
+			return status, ctx.Err()
+		case <-time.After(pollInterval):
+		}
+	}
+}
+
+// ReadFile carves path on the host with uuid and returns the contents of the file. It
+// fails if path matches anything other than exactly one regular file.
+func ReadFile(ctx context.Context, carver models.FileCarver, uuid, path string) ([]byte, error) {
+	archive, err := Download(ctx, carver, uuid, path, nil)
+	if err != nil {
+		return nil, err
+	}
+	_, contents, ok, err := ExtractFile(archive)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		return nil, fmt.Errorf("Carve of %s did not contain exactly one file", path)
+	}
+	return contents, nil
+}
+
+// ExtractFile returns the name and contents of the single regular file in a carve archive.
+// ok is false if the archive holds more or less than one file, in which case the archive
+// itself is the most useful thing to keep. An error means the archive is corrupt.
+func ExtractFile(archive []byte) (name string, contents []byte, ok bool, err error) {
+	reader := tar.NewReader(bytes.NewReader(archive))
+	files := 0
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", nil, false, fmt.Errorf("Carve archive is invalid: %s", err)
+		}
+		if header.Typeflag != tar.TypeReg {
+			continue
+		}
+		files++
+		if files > 1 {
+			// Keep reading so a corrupt archive is still reported
+			continue
+		}
+		name = header.Name
+		contents, err = ioutil.ReadAll(reader)
+		if err != nil {
+			return "", nil, false, fmt.Errorf("Carve archive is invalid: %s", err)
+		}
+	}
+	if files != 1 {
+		return "", nil, false, nil
+	}
+	return name, contents, true, nil
+}
@@ -0,0 +1,103 @@
+package carve
+
+import (
+	"archive/tar"
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// blockCarver serves a carve already uploaded as blocks, reporting size as its size
+type blockCarver struct {
+	blocks [][]byte
+	size   int64
+}
+
+func (carver *blockCarver) StartCarve(ctx context.Context, uuid string, path string) (string, error) {
+	return "carve", nil
+}
+
+func (carver *blockCarver) CarveStatus(ctx context.Context, carveID string) (models.CarveStatus, error) {
+	return models.CarveStatus{
+		Started:        true,
+		BlockCount:     len(carver.blocks),
+		BlocksReceived: len(carver.blocks),
+		CarveSize:      carver.size,
+	}, nil
+}
+
+func (carver *blockCarver) FetchCarveBlock(ctx context.Context, carveID string, blockID int) ([]byte, error) {
+	if blockID >= len(carver.blocks) {
+		return nil, fmt.Errorf("no block %d", blockID)
+	}
+	return carver.blocks[blockID], nil
+}
+
+// newBlockCarver splits a tar archive holding files into blocks of blockSize bytes
+func newBlockCarver(t *testing.T, blockSize int, files map[string]string) *blockCarver {
+	archive := &bytes.Buffer{}
+	writer := tar.NewWriter(archive)
+	for name, contents := range files {
+		writer.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(contents)), Typeflag: tar.TypeReg})
+		writer.Write([]byte(contents))
+	}
+	if err := writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	carver := &blockCarver{size: int64(archive.Len())}
+	for data := archive.Bytes(); len(data) > 0; {
+		size := blockSize
+		if size > len(data) {
+			size = len(data)
+		}
+		carver.blocks = append(carver.blocks, data[:size])
+		data = data[size:]
+	}
+	return carver
+}
+
+func TestReadFileReassemblesBlocks(t *testing.T) {
+	contents := strings.Repeat("carved ", 500)
+	carver := newBlockCarver(t, 1000, map[string]string{"etc/hosts": contents})
+	if len(carver.blocks) < 3 {
+		t.Fatalf("expected the archive to span several blocks, got %d", len(carver.blocks))
+	}
+	read, err := ReadFile(context.Background(), carver, "host", "/etc/hosts")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(read) != contents {
+		t.Fatalf("ReadFile() returned %d bytes, want %d", len(read), len(contents))
+	}
+}
+
+func TestReadFileNeedsExactlyOneFile(t *testing.T) {
+	carver := newBlockCarver(t, 1000, map[string]string{"a": "1", "b": "2"})
+	if _, err := ReadFile(context.Background(), carver, "host", "/tmp/*"); err == nil {
+		t.Fatal("expected an error for a carve of two files")
+	}
+}
+
+func TestDownloadChecksCarveSize(t *testing.T) {
+	carver := newBlockCarver(t, 512, map[string]string{"a": "contents"})
+	tests := []struct {
+		size    int64
+		wantErr string
+	}{
+		{carver.size + 1, "but the host reported"},
+		{carver.size - 1, "larger than the"},
+		{MaxCarveSize + 1, "more than the"},
+		{-1, "more than the"},
+	}
+	for _, test := range tests {
+		carver.size = test.size
+		_, err := Download(context.Background(), carver, "host", "/a", nil)
+		if err == nil || !strings.Contains(err.Error(), test.wantErr) {
+			t.Errorf("size %d: Download() error = %v, want %q", test.size, err, test.wantErr)
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Command goquery is the goquery shell with every built in API driver. The driver is
+// picked by apiDriver in the config file and configured by its block under drivers.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2"
+	"github.com/AbGuthrie/goquery/v2/api"
+	"github.com/AbGuthrie/goquery/v2/config"
+
+	// Built in drivers register themselves with the api package when imported
+	_ "github.com/AbGuthrie/goquery/v2/api/fleet"
+	_ "github.com/AbGuthrie/goquery/v2/api/local"
+	_ "github.com/AbGuthrie/goquery/v2/api/mock"
+	_ "github.com/AbGuthrie/goquery/v2/api/osctrl"
+	_ "github.com/AbGuthrie/goquery/v2/api/tlsserver"
+	_ "github.com/AbGuthrie/goquery/v2/api/uptycs"
+)
+
+func main() {
+	configOverride := flag.String("config", "", "Path to a goquery config file")
+	commandString := flag.String("c", "", "Run ';' separated commands then exit")
+	scriptPath := flag.String("f", "", "Run the commands in a script file then exit")
+	continueOnError := flag.Bool("continue-on-error", false, "Keep running a script after a command fails")
+	listDrivers := flag.Bool("drivers", false, "List the available API drivers then exit")
+	flag.Parse()
+
+	if *listDrivers {
+		fmt.Println(strings.Join(api.Drivers(), "\n"))
+		return
+	}
+
+	// Unlike the mock example there is no fallback config, without one there is no driver
+	cfg, err := config.LoadUserConfig(*configOverride)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	goqueryAPI, err := api.Create(cfg.APIDriver, cfg.Drivers[cfg.APIDriver], cfg.DebugEnabled)
+	if err != nil {
+		fmt.Printf("Encountered an error starting API: %s\n", err)
+		os.Exit(1)
+	}
+
+	script, err := goquery.OpenScript(*commandString, *scriptPath)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	if script == nil {
+		goquery.Run(goqueryAPI, cfg)
+		return
+	}
+	defer script.Close()
+	if err := goquery.RunScript(goqueryAPI, cfg, script, os.Stdout, *continueOnError); err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+}
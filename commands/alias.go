@@ -5,16 +5,13 @@ import (
 	"sort"
 	"strings"
 
-	"github.com/AbGuthrie/goquery/v2/models"
-
-	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/utils"
+	"github.com/AbGuthrie/goquery/v2/session"
 
 	prompt "github.com/c-bata/go-prompt"
 )
 
-func printAliases(config *config.Config) {
-	aliases := config.Aliases
+func printAliases(s *session.Session) {
+	aliases := s.Config.Aliases
 	aliasNames := make([]string, 0)
 	for name := range aliases {
 		aliasNames = append(aliasNames, name)
@@ -31,15 +28,15 @@ func printAliases(config *config.Config) {
 		})
 	}
 
-	utils.PrettyPrintQueryResults(aliasRows, config.PrintMode)
+	s.PrintRows(aliasRows)
 }
 
-func alias(api models.GoQueryAPI, config *config.Config, cmdline string) error {
+func alias(s *session.Session, cmdline string) error {
 	args := strings.Split(cmdline, " ")
 
 	// If no args provided, print current state of aliases
 	if len(args) == 1 {
-		printAliases(config)
+		printAliases(s)
 		return nil
 	}
 
@@ -56,12 +53,12 @@ func alias(api models.GoQueryAPI, config *config.Config, cmdline string) error {
 		}
 
 		// Create the command and store in state
-		err := config.AddAlias(name, command)
+		err := s.Config.AddAlias(name, command)
 		if err != nil {
 			return fmt.Errorf(fmt.Sprintf("Error creating alias: %s\n", err))
 		}
 
-		fmt.Printf("Created new alias '%s' with command: %s\n", name, command)
+		fmt.Fprintf(s.Out, "Created new alias '%s' with command: %s\n", name, command)
 		return nil
 	}
 
@@ -74,10 +71,10 @@ func alias(api models.GoQueryAPI, config *config.Config, cmdline string) error {
 	}
 
 	// Argument provided, try remove alias from config
-	if err := config.RemoveAlias(args[2]); err != nil {
+	if err := s.Config.RemoveAlias(args[2]); err != nil {
 		return err
 	}
-	fmt.Printf("Successfully removed alias\n")
+	fmt.Fprintf(s.Out, "Successfully removed alias\n")
 	return nil
 }
 
@@ -87,7 +84,7 @@ func aliasHelp() string {
 		"To remove an alias, use .alias --remove ALIAS_NAME"
 }
 
-func aliasSuggest(cmdline string) []prompt.Suggest {
+func aliasSuggest(s *session.Session, cmdline string) []prompt.Suggest {
 	// If just at .alias, suggest the flags
 	args := strings.Split(cmdline, " ")
 	if len(args) == 2 && args[1] == "" {
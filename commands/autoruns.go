@@ -0,0 +1,112 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+// autorunSource is a table that records something started automatically. Every query
+// selects the same source, name, command and location columns so their rows line up.
+type autorunSource struct {
+	table     string
+	platforms []string
+	query     string
+}
+
+var autorunSources = []autorunSource{
+	{"crontab", []string{"linux", "darwin"},
+		"select 'crontab' as source, minute || ' ' || hour || ' ' || day_of_month || ' ' || month || ' ' || day_of_week as name, " +
+			"command, path as location from crontab"},
+	{"systemd_units", []string{"linux"},
+		"select 'systemd_units' as source, id as name, description as command, fragment_path as location from systemd_units " +
+			"where id like '%.service' or id like '%.timer'"},
+	{"startup_items", []string{"linux", "darwin", "windows"},
+		"select 'startup_items' as source, name, trim(path || ' ' || args) as command, startup_items.source as location from startup_items"},
+	{"launchd", []string{"darwin"},
+		"select 'launchd' as source, label as name, coalesce(nullif(program, ''), program_arguments) as command, path as location from launchd " +
+			"where run_at_load = '1' or keep_alive = '1'"},
+	{"services", []string{"windows"},
+		`select 'services' as source, name, path as command, 'HKEY_LOCAL_MACHINE\SYSTEM\CurrentControlSet\Services\' || name as location ` +
+			"from services where start_type in ('AUTO_START', 'BOOT_START', 'SYSTEM_START')"},
+	{"scheduled_tasks", []string{"windows"},
+		"select 'scheduled_tasks' as source, name, action as command, path as location from scheduled_tasks where enabled = 1"},
+	{"registry", []string{"windows"},
+		`select 'registry' as source, name, data as command, key as location from registry ` +
+			`where key like 'HKEY_LOCAL_MACHINE\Software\Microsoft\Windows\CurrentVersion\Run%' ` +
+			`or key like 'HKEY_USERS\%\Software\Microsoft\Windows\CurrentVersion\Run%'`},
+}
+
+func autoruns(s *session.Session, cmdline string) error {
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return fmt.Errorf("No host is currently connected: %s", err)
+	}
+
+	platform := autorunPlatform(host)
+	sources := make([]string, 0)
+	queries := make([]string, 0)
+	for _, source := range autorunSources {
+		if !containsString(source.platforms, platform) {
+			continue
+		}
+		if !hostHasTable(host, source.table) {
+			fmt.Fprintf(s.Out, "Skipping %s, the table is not available on this host\n", source.table)
+			continue
+		}
+		sources = append(sources, source.table)
+		queries = append(queries, source.query)
+	}
+	if len(queries) == 0 {
+		return fmt.Errorf("None of the %s autorun tables are available on this host", platform)
+	}
+
+	// Each source is its own query so a table missing on the host, which isn't known if
+	// the host's tables couldn't be listed, only loses that source
+	results := make(models.Rows, 0)
+	failed := 0
+	for i, result := range s.ScheduleQueriesAndWait(host.UUID, queries) {
+		if result.Err != nil {
+			fmt.Fprintf(s.Out, "Skipping %s: %s\n", sources[i], result.Err)
+			failed++
+			continue
+		}
+		results = append(results, result.Rows...)
+	}
+	if failed == len(queries) {
+		return fmt.Errorf("None of the %s autorun tables could be queried on this host", platform)
+	}
+
+	s.PrintResults(results)
+	return nil
+}
+
+// autorunPlatform picks the linux, darwin or windows autorun tables for host. The platform
+// reported by each API differs so the tables only present on one OS are checked as well.
+func autorunPlatform(host hosts.Host) string {
+	platform := strings.ToLower(host.Platform)
+	switch {
+	case strings.Contains(platform, "windows"):
+		return "windows"
+	case strings.Contains(platform, "darwin") || strings.Contains(platform, "mac"):
+		return "darwin"
+	case containsString(host.Tables, "registry"):
+		return "windows"
+	case containsString(host.Tables, "launchd"):
+		return "darwin"
+	}
+	return "linux"
+}
+
+func autorunsHelp() string {
+	return "List the programs the current host starts automatically, from the persistence tables for its platform"
+}
+
+func autorunsSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	return []prompt.Suggest{}
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/internal/apitest"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestAutorunsSkipsFailingSources(t *testing.T) {
+	// The stub host has no table list, so every linux source is tried and only crontab exists
+	api := apitest.New(map[string]models.Rows{
+		autorunSources[0].query: {{"source": "crontab", "name": "* * * * *", "command": "/usr/bin/backup", "location": "/etc/crontab"}},
+	})
+	s, out := newTestSession(t, api, "h")
+	if err := autoruns(s, ".autoruns"); err != nil {
+		t.Fatal(err)
+	}
+	if len(api.Scheduled()) != 3 {
+		t.Fatalf("expected a query per linux source, scheduled %q", api.Scheduled())
+	}
+	for _, query := range api.Scheduled() {
+		if strings.Contains(query, "union") {
+			t.Fatalf("sources should be queried separately: %s", query)
+		}
+	}
+	for _, want := range []string{"/usr/bin/backup", "Skipping systemd_units: ", "Skipping startup_items: "} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output is missing %q:\n%s", want, out)
+		}
+	}
+
+	api.RemoveResults(autorunSources[0].query)
+	if err := autoruns(s, ".autoruns"); err == nil {
+		t.Fatal("expected an error when no source could be queried")
+	}
+}
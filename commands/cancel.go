@@ -0,0 +1,32 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/jobs"
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+func cancel(s *session.Session, cmdline string) error {
+	args := strings.Split(cmdline, " ") // Separate command and arguments
+	if len(args) != 2 {
+		return fmt.Errorf("A job name to cancel must be provided")
+	}
+
+	if err := s.Jobs.Cancel(args[1]); err != nil {
+		return err
+	}
+	fmt.Fprintf(s.Out, "Stopped waiting on '%s'\n", args[1])
+	return nil
+}
+
+func cancelHelp() string {
+	return "Stop tracking a pending scheduled query"
+}
+
+func cancelSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	return jobSuggest(s, func(job jobs.Job) bool { return job.Status == jobs.StatusPending })
+}
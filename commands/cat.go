@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/AbGuthrie/goquery/v2/session"
+	"github.com/AbGuthrie/goquery/v2/utils"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+// binarySniffLength is how much of a file is inspected when deciding if it is binary
+const binarySniffLength = 8000
+
+// hexDumpLineLength is the number of bytes shown on each line of a hexdump
+const hexDumpLineLength = 16
+
+func cat(s *session.Session, cmdline string) error {
+	args := strings.Fields(cmdline)
+	if len(args) < 2 {
+		return fmt.Errorf("Usage: .cat PATH")
+	}
+	return printRemoteFile(s, strings.TrimSpace(cmdline[len(args[0]):]), -1)
+}
+
+func catHelp() string {
+	return "Print a file on the current host, binary files are shown as a hexdump"
+}
+
+func catSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	return remotePathSuggest(s, cmdline, false)
+}
+
+// printRemoteFile reads remotePath from the current host and prints at most lines
+// lines of it, or all of it if lines is negative
+func printRemoteFile(s *session.Session, remotePath string, lines int) error {
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return fmt.Errorf("No host is currently connected: %s", err)
+	}
+
+	contents, err := s.ReadRemoteFile(host.UUID, resolveRemotePath(host, remotePath))
+	if err != nil {
+		return err
+	}
+
+	err = printFileContents(s.PagedOut(), contents, lines)
+	if err == utils.ErrPagerQuit {
+		return nil
+	}
+	return err
+}
+
+// printFileContents writes contents to w, as a hexdump if they look binary
+func printFileContents(w io.Writer, contents []byte, lines int) error {
+	if isBinary(contents) {
+		if lines >= 0 && len(contents) > lines*hexDumpLineLength {
+			contents = contents[:lines*hexDumpLineLength]
+		}
+		dumper := hex.Dumper(w)
+		if _, err := dumper.Write(contents); err != nil {
+			return err
+		}
+		return dumper.Close()
+	}
+
+	if lines >= 0 {
+		contents = firstLines(contents, lines)
+	}
+	if _, err := w.Write(contents); err != nil {
+		return err
+	}
+	if len(contents) > 0 && contents[len(contents)-1] != '\n' {
+		_, err := io.WriteString(w, "\n")
+		return err
+	}
+	return nil
+}
+
+// isBinary reports whether the start of contents contains a NUL byte or isn't UTF-8
+func isBinary(contents []byte) bool {
+	sniff := contents
+	if len(sniff) > binarySniffLength {
+		sniff = sniff[:binarySniffLength]
+		// Don't count a multi-byte character cut in half as invalid
+		for i := 0; i < utf8.UTFMax && !utf8.Valid(sniff); i++ {
+			sniff = sniff[:len(sniff)-1]
+		}
+	}
+	return bytes.IndexByte(sniff, 0) != -1 || !utf8.Valid(sniff)
+}
+
+// firstLines returns contents up to and including the nth newline
+func firstLines(contents []byte, n int) []byte {
+	end := 0
+	for i := 0; i < n; i++ {
+		next := bytes.IndexByte(contents[end:], '\n')
+		if next == -1 {
+			return contents
+		}
+		end += next + 1
+	}
+	return contents[:end]
+}
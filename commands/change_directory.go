@@ -2,21 +2,17 @@ package commands
 
 import (
 	"fmt"
-	"path/filepath"
 	"strings"
 
-	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/hosts"
-	"github.com/AbGuthrie/goquery/v2/models"
-	"github.com/AbGuthrie/goquery/v2/utils"
+	"github.com/AbGuthrie/goquery/v2/session"
 
 	prompt "github.com/c-bata/go-prompt"
 )
 
-var verificationTemplate = "select * from file where path = '%s' and type = 'directory'"
+var verificationTemplate = "select * from file where path = %s and type = 'directory'"
 
-func changeDirectory(api models.GoQueryAPI, config *config.Config, cmdline string) error {
-	host, err := hosts.GetCurrentHost()
+func changeDirectory(s *session.Session, cmdline string) error {
+	host, err := s.Hosts.GetCurrentHost()
 	if err != nil {
 		return fmt.Errorf("No host is currently connected: %s", err)
 	}
@@ -35,18 +31,15 @@ func changeDirectory(api models.GoQueryAPI, config *config.Config, cmdline strin
 	}
 
 	// The change isn't absolute so we need the current directory
-	if requestedDirectory[0] != '/' {
-		requestedDirectory = host.CurrentDirectory + requestedDirectory
-	}
-	requestedDirectory = filepath.Clean(requestedDirectory)
+	requestedDirectory = resolveRemotePath(host, requestedDirectory)
 
 	// All directory changes must end with a forward slash
 	if requestedDirectory[len(requestedDirectory)-1] != '/' {
 		requestedDirectory += "/"
 	}
 
-	verificationQuery := fmt.Sprintf(verificationTemplate, requestedDirectory)
-	results, err := utils.ScheduleQueryAndWait(api, host.UUID, verificationQuery)
+	verificationQuery := fmt.Sprintf(verificationTemplate, sqlString(requestedDirectory))
+	results, err := s.ScheduleQueryAndWait(host.UUID, verificationQuery)
 
 	if err != nil {
 		return err
@@ -56,13 +49,13 @@ func changeDirectory(api models.GoQueryAPI, config *config.Config, cmdline strin
 		return fmt.Errorf("No such directory")
 	}
 
-	return hosts.SetCurrentHostDirectory(requestedDirectory)
+	return s.Hosts.SetCurrentHostDirectory(requestedDirectory)
 }
 
 func changeDirectoryHelp() string {
 	return "Change directories on a remote host"
 }
 
-func changeDirectorySuggest(cmdline string) []prompt.Suggest {
-	return []prompt.Suggest{}
+func changeDirectorySuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	return remotePathSuggest(s, cmdline, true)
 }
@@ -0,0 +1,61 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/internal/apitest"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestChangeDirectory(t *testing.T) {
+	tests := []struct {
+		cmdline string
+		want    string
+	}{
+		{".cd /var", "/var/"},
+		{".cd ..", "/"},
+		{".cd ../usr/./lib/", "/usr/lib/"},
+		{".cd ssh", "/etc/ssh/"},
+		{".cd it's", "/etc/it's/"},
+	}
+	for _, test := range tests {
+		query := "select * from file where path = " + sqlString(test.want) + " and type = 'directory'"
+		api := apitest.New(map[string]models.Rows{
+			query: {{"path": test.want, "type": "directory"}},
+		})
+		s, _ := newTestSession(t, api, "h")
+		s.Hosts.SetCurrentHostDirectory("/etc/")
+		if err := changeDirectory(s, test.cmdline); err != nil {
+			t.Errorf("%s: %s", test.cmdline, err)
+			continue
+		}
+		host, _ := s.Hosts.GetCurrentHost()
+		if host.CurrentDirectory != test.want {
+			t.Errorf("%s changed to %s, want %s", test.cmdline, host.CurrentDirectory, test.want)
+		}
+	}
+}
+
+func TestChangeDirectoryQuotesThePath(t *testing.T) {
+	api := apitest.New(nil)
+	s, _ := newTestSession(t, api, "h")
+	changeDirectory(s, ".cd /x' or '1'='1")
+	want := "select * from file where path = '/x'' or ''1''=''1/' and type = 'directory'"
+	if scheduled := api.Scheduled(); len(scheduled) != 1 || scheduled[0] != want {
+		t.Fatalf("scheduled %q, want %q", scheduled, want)
+	}
+}
+
+func TestChangeDirectoryToAMissingDirectory(t *testing.T) {
+	api := apitest.New(map[string]models.Rows{
+		"select * from file where path = '/missing/' and type = 'directory'": {},
+	})
+	s, _ := newTestSession(t, api, "h")
+	if err := changeDirectory(s, ".cd /missing"); err == nil {
+		t.Fatal("cd to a missing directory did not fail")
+	}
+	host, _ := s.Hosts.GetCurrentHost()
+	if host.CurrentDirectory != "/" {
+		t.Fatalf("current directory changed to %s", host.CurrentDirectory)
+	}
+}
@@ -2,17 +2,16 @@ package commands
 
 import (
 	"fmt"
-	"os"
 	"os/exec"
 	"runtime"
 	"strings"
 
-	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
+
 	prompt "github.com/c-bata/go-prompt"
 )
 
-func clear(api models.GoQueryAPI, config *config.Config, cmdline string) error {
+func clear(s *session.Session, cmdline string) error {
 	args := strings.Split(cmdline, " ") // Separate command and arguments
 	if len(args) > 1 {
 		return fmt.Errorf("This command takes no parameters")
@@ -23,10 +22,10 @@ func clear(api models.GoQueryAPI, config *config.Config, cmdline string) error {
 	currentOS := runtime.GOOS
 	if currentOS == "windows" {
 		cmd := exec.Command("cmd", "/c", "cls")
-		cmd.Stdout = os.Stdout
+		cmd.Stdout = s.Out
 		cmd.Run()
 	} else {
-		print("\033[H\033[2J")
+		fmt.Fprint(s.Out, "\033[H\033[2J")
 	}
 	return nil
 }
@@ -35,6 +34,6 @@ func clearHelp() string {
 	return "Clear the terminal screen"
 }
 
-func clearSuggest(cmdline string) []prompt.Suggest {
+func clearSuggest(s *session.Session, cmdline string) []prompt.Suggest {
 	return []prompt.Suggest{}
 }
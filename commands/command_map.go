@@ -3,43 +3,56 @@ package commands
 import (
 	"errors"
 
-	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/models"
-	prompt "github.com/c-bata/go-prompt"
+	"github.com/AbGuthrie/goquery/v2/session"
 )
 
 // GoQueryCommand defines the functions required to add a new command to goquery
-type GoQueryCommand struct {
-	Execute     func(models.GoQueryAPI, *config.Config, string) error
-	Help        func() string
-	Suggestions func(string) []prompt.Suggest
-}
-
-// CommandMap is the mapping from command line string to GoQueryCommand
-// structure
-var CommandMap map[string]GoQueryCommand
+type GoQueryCommand = session.Command
 
 // Errors
 var errArgumentError error
 var errRuntimeError error
 
 func init() {
-	CommandMap = map[string]GoQueryCommand{
-		".alias":      GoQueryCommand{alias, aliasHelp, aliasSuggest},
-		".connect":    GoQueryCommand{connect, connectHelp, connectSuggest},
-		".clear":      GoQueryCommand{clear, clearHelp, clearSuggest},
-		".disconnect": GoQueryCommand{disconnect, disconnectHelp, disconnectSuggest},
-		".exit":       GoQueryCommand{exit, exitHelp, exitSuggest},
-		".help":       GoQueryCommand{help, helpHelp, helpSuggest},
-		".history":    GoQueryCommand{history, historyHelp, historySuggest},
-		".hosts":      GoQueryCommand{printHosts, printHostsHelp, printHostsSuggest},
-		".mode":       GoQueryCommand{changeMode, changeModeHelp, changeModeSuggest},
-		".query":      GoQueryCommand{query, queryHelp, querySuggest},
-		".resume":     GoQueryCommand{resume, resumeHelp, resumeSuggest},
-		".schedule":   GoQueryCommand{schedule, scheduleHelp, scheduleSuggest},
-		"ls":          GoQueryCommand{listDirectory, listDirectoryHelp, listDirectorySuggest},
-		"cd":          GoQueryCommand{changeDirectory, changeDirectoryHelp, changeDirectorySuggest},
-	}
 	errArgumentError = errors.New("The arguments provided were incorrect for the command")
 	errRuntimeError = errors.New("There was a problem executing the command")
 }
+
+// DefaultCommands returns a new mapping from command line string to GoQueryCommand
+// structure for every built in command. Each session gets its own copy so commands
+// can be added or removed without affecting other sessions.
+func DefaultCommands() map[string]GoQueryCommand {
+	return map[string]GoQueryCommand{
+		".alias":      GoQueryCommand{Execute: alias, Help: aliasHelp, Suggestions: aliasSuggest},
+		".autoruns":   GoQueryCommand{Execute: autoruns, Help: autorunsHelp, Suggestions: autorunsSuggest},
+		".cancel":     GoQueryCommand{Execute: cancel, Help: cancelHelp, Suggestions: cancelSuggest},
+		".cat":        GoQueryCommand{Execute: cat, Help: catHelp, Suggestions: catSuggest},
+		".connect":    GoQueryCommand{Execute: connect, Help: connectHelp, Suggestions: connectSuggest},
+		".clear":      GoQueryCommand{Execute: clear, Help: clearHelp, Suggestions: clearSuggest},
+		".diff":       GoQueryCommand{Execute: diff, Help: diffHelp, Suggestions: diffSuggest},
+		".disconnect": GoQueryCommand{Execute: disconnect, Help: disconnectHelp, Suggestions: disconnectSuggest},
+		".download":   GoQueryCommand{Execute: download, Help: downloadHelp, Suggestions: downloadSuggest},
+		".exit":       GoQueryCommand{Execute: exit, Help: exitHelp, Suggestions: exitSuggest},
+		".export":     GoQueryCommand{Execute: export, Help: exportHelp, Suggestions: exportSuggest},
+		".help":       GoQueryCommand{Execute: help, Help: helpHelp, Suggestions: helpSuggest},
+		".history":    GoQueryCommand{Execute: history, Help: historyHelp, Suggestions: historySuggest},
+		".find":       GoQueryCommand{Execute: find, Help: findHelp, Suggestions: findSuggest},
+		".hash":       GoQueryCommand{Execute: hash, Help: hashHelp, Suggestions: hashSuggest},
+		".head":       GoQueryCommand{Execute: head, Help: headHelp, Suggestions: headSuggest},
+		".hosts":      GoQueryCommand{Execute: printHosts, Help: printHostsHelp, Suggestions: printHostsSuggest},
+		".jobs":       GoQueryCommand{Execute: listJobs, Help: listJobsHelp, Suggestions: listJobsSuggest},
+		".mode":       GoQueryCommand{Execute: changeMode, Help: changeModeHelp, Suggestions: changeModeSuggest},
+		".netstat":    GoQueryCommand{Execute: netstat, Help: netstatHelp, Suggestions: netstatSuggest},
+		".ps":         GoQueryCommand{Execute: ps, Help: psHelp, Suggestions: psSuggest},
+		".pstree":     GoQueryCommand{Execute: pstree, Help: pstreeHelp, Suggestions: pstreeSuggest},
+		".query":      GoQueryCommand{Execute: query, Help: queryHelp, Suggestions: querySuggest},
+		".resume":     GoQueryCommand{Execute: resume, Help: resumeHelp, Suggestions: resumeSuggest},
+		".schedule":   GoQueryCommand{Execute: schedule, Help: scheduleHelp, Suggestions: scheduleSuggest},
+		".schema":     GoQueryCommand{Execute: printSchema, Help: printSchemaHelp, Suggestions: printSchemaSuggest},
+		".sweep":      GoQueryCommand{Execute: sweep, Help: sweepHelp, Suggestions: sweepSuggest},
+		".watch":      GoQueryCommand{Execute: watch, Help: watchHelp, Suggestions: watchSuggest},
+		".wait":       GoQueryCommand{Execute: wait, Help: waitHelp, Suggestions: waitSuggest},
+		"ls":          GoQueryCommand{Execute: listDirectory, Help: listDirectoryHelp, Suggestions: listDirectorySuggest},
+		"cd":          GoQueryCommand{Execute: changeDirectory, Help: changeDirectoryHelp, Suggestions: changeDirectorySuggest},
+	}
+}
@@ -0,0 +1,27 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/config"
+	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
+)
+
+// newTestSession returns a session with every built in command, nothing stored on disk
+// and the hosts in uuids connected, the last one current
+func newTestSession(t *testing.T, api models.GoQueryAPI, uuids ...string) (*session.Session, *bytes.Buffer) {
+	out := &bytes.Buffer{}
+	s := session.New(api, config.Config{}, DefaultCommands(), out)
+	for _, uuid := range uuids {
+		host, err := api.CheckHost(uuid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := s.Hosts.Register(host); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s, out
+}
@@ -1,36 +1,39 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
-	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/hosts"
 	"github.com/AbGuthrie/goquery/v2/models"
-	"github.com/AbGuthrie/goquery/v2/utils"
+	"github.com/AbGuthrie/goquery/v2/session"
 
 	prompt "github.com/c-bata/go-prompt"
 )
 
-func connect(api models.GoQueryAPI, config *config.Config, cmdline string) error {
+func connect(s *session.Session, cmdline string) error {
 	args := strings.Split(cmdline, " ") // Separate command and arguments
 	if len(args) == 1 {
 		return fmt.Errorf("Host UUID required")
 	}
 	uuid := args[1]
-	host, err := api.CheckHost(uuid)
+	ctx, cancel := s.QueryContext()
+	host, err := s.ContextAPI().CheckHostContext(ctx, uuid)
+	cancel()
+	if errors.Is(err, models.ErrHostNotFound) {
+		return fmt.Errorf("No host with UUID %s is registered with the backend", uuid)
+	}
 	if err != nil {
 		return err
 	}
 
 	// All is good, update hosts state
-	if err := hosts.Register(host); err != nil {
+	if err := s.Hosts.Register(host); err != nil {
 		return fmt.Errorf("Error connecting to host: %s", err)
 	}
-	fmt.Printf("Verified Host(%s) Exists.\n", uuid)
+	fmt.Fprintf(s.Out, "Verified Host(%s) Exists.\n", uuid)
 
-	results, err := utils.ScheduleQueryAndWait(
-		api,
+	results, err := s.ScheduleQueryAndWait(
 		host.UUID,
 		"select name from osquery_registry where registry = 'table' and active = 1",
 	)
@@ -46,18 +49,16 @@ func connect(api models.GoQueryAPI, config *config.Config, cmdline string) error
 			tables = append(tables, table)
 		}
 	}
-	hosts.SetHostTables(host.UUID, tables)
-
-	return nil
+	return s.Hosts.SetHostTables(host.UUID, tables)
 }
 
 func connectHelp() string {
 	return "Connect to a host with UUID"
 }
 
-func connectSuggest(cmdline string) []prompt.Suggest {
+func connectSuggest(s *session.Session, cmdline string) []prompt.Suggest {
 	prompts := []prompt.Suggest{}
-	for _, host := range hosts.GetCurrentHosts() {
+	for _, host := range s.Hosts.GetCurrentHosts() {
 		prompts = append(prompts, prompt.Suggest{host.UUID, host.ComputerName})
 	}
 	return prompts
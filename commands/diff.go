@@ -0,0 +1,162 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
+	"github.com/AbGuthrie/goquery/v2/utils"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+func diff(s *session.Session, cmdline string) error {
+	args := strings.Fields(cmdline)
+	if len(args) == 4 && args[1] == "--files" {
+		return diffFiles(s, args[2], args[3])
+	}
+	if len(args) < 4 {
+		return fmt.Errorf("Usage: .diff UUID_A UUID_B QUERY or .diff --files A.json B.json")
+	}
+
+	connected := s.Hosts.GetCurrentHosts()
+	targets := make([]hosts.Host, 0, 2)
+	for _, uuid := range args[1:3] {
+		host, ok := findConnectedHost(connected, uuid)
+		if !ok {
+			return fmt.Errorf("No active host connection with uuid %s", uuid)
+		}
+		targets = append(targets, host)
+	}
+	// The query is everything after the command and both UUIDs
+	query := cmdline
+	for _, arg := range args[:3] {
+		query = strings.TrimSpace(query)[len(arg):]
+	}
+	query = strings.TrimSpace(query)
+
+	hostResults := s.ScheduleQueryOnHostsAndWait(targets, query)
+	for _, result := range hostResults {
+		if result.Err != nil {
+			return fmt.Errorf("Query failed on %s: %s", result.Host.UUID, result.Err)
+		}
+	}
+	printDiff(s, hostResults[0].Rows, hostResults[1].Rows, targets[0].UUID, targets[1].UUID)
+	return nil
+}
+
+// diffFiles compares two result sets saved with .export in json or ndjson mode
+func diffFiles(s *session.Session, pathA, pathB string) error {
+	rowsA, err := readResultsFile(pathA)
+	if err != nil {
+		return err
+	}
+	rowsB, err := readResultsFile(pathB)
+	if err != nil {
+		return err
+	}
+	printDiff(s, rowsA, rowsB, pathA, pathB)
+	return nil
+}
+
+// printDiff prints the rows only in rowsA or only in rowsB, with a side column naming
+// which of them each row came from
+func printDiff(s *session.Session, rowsA, rowsB models.Rows, nameA, nameB string) {
+	onlyA, onlyB, _ := utils.DiffRows(rowsA, rowsB, nil)
+	if len(onlyA) == 0 && len(onlyB) == 0 {
+		fmt.Fprintf(s.Out, "No differences between %s and %s\n", nameA, nameB)
+		return
+	}
+	s.PrintResults(append(utils.TagRows(onlyA, "side", nameA), utils.TagRows(onlyB, "side", nameB)...))
+}
+
+// readResultsFile reads results saved as a json array or as ndjson. Values that aren't
+// strings are converted back to their JSON text.
+func readResultsFile(path string) (models.Rows, error) {
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read %s: %s", path, err)
+	}
+
+	objects := make([]map[string]interface{}, 0)
+	decoder := json.NewDecoder(bytes.NewReader(contents))
+	decoder.UseNumber()
+	if trimmed := bytes.TrimSpace(contents); len(trimmed) > 0 && trimmed[0] == '[' {
+		err = decoder.Decode(&objects)
+	} else {
+		for {
+			object := make(map[string]interface{})
+			if err = decoder.Decode(&object); err != nil {
+				break
+			}
+			objects = append(objects, object)
+		}
+		if err == io.EOF {
+			err = nil
+		}
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%s is not a json or ndjson results file: %s", path, err)
+	}
+
+	rows := make(models.Rows, 0, len(objects))
+	for _, object := range objects {
+		row := make(map[string]string, len(object))
+		for column, value := range object {
+			switch typed := value.(type) {
+			case string:
+				row[column] = typed
+			case nil:
+				row[column] = ""
+			default:
+				encoded, _ := json.Marshal(typed)
+				row[column] = string(encoded)
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+func diffHelp() string {
+	return "Run a query on two connected hosts and print the rows unique to each, " +
+		"or compare two result files saved with .export using --files"
+}
+
+func diffSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	args := strings.Split(cmdline, " ")
+	// Nothing past the command name has been typed yet
+	if len(args) < 2 {
+		return []prompt.Suggest{}
+	}
+	switch {
+	case len(args) == 2:
+		prompts := []prompt.Suggest{{Text: "--files", Description: "Compare two json or ndjson result files"}}
+		for _, host := range s.Hosts.GetCurrentHosts() {
+			prompts = append(prompts, prompt.Suggest{Text: host.UUID, Description: host.ComputerName})
+		}
+		return prompt.FilterHasPrefix(prompts, args[1], false)
+	case args[1] == "--files":
+		return []prompt.Suggest{}
+	case len(args) == 3:
+		prompts := []prompt.Suggest{}
+		for _, host := range s.Hosts.GetCurrentHosts() {
+			if host.UUID != args[1] {
+				prompts = append(prompts, prompt.Suggest{Text: host.UUID, Description: host.ComputerName})
+			}
+		}
+		return prompt.FilterHasPrefix(prompts, args[2], false)
+	}
+
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return []prompt.Suggest{}
+	}
+	return sqlSuggest(s, host, strings.Join(args[3:], " "))
+}
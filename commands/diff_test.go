@@ -0,0 +1,30 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/internal/apitest"
+)
+
+func TestDiffSuggest(t *testing.T) {
+	s, _ := newTestSession(t, apitest.New(nil), "a", "b")
+	tests := []struct {
+		cmdline string
+		want    []string
+	}{
+		{".diff", []string{}},
+		{".diff ", []string{"--files", "a", "b"}},
+		{".diff --files ", []string{}},
+		{".diff a ", []string{"b"}},
+	}
+	for _, test := range tests {
+		got := make([]string, 0)
+		for _, suggestion := range diffSuggest(s, test.cmdline) {
+			got = append(got, suggestion.Text)
+		}
+		if !reflect.DeepEqual(got, test.want) {
+			t.Errorf("diffSuggest(%q) = %v, want %v", test.cmdline, got, test.want)
+		}
+	}
+}
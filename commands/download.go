@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"io/ioutil"
+	"path"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/carve"
+	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+func download(s *session.Session, cmdline string) error {
+	args := strings.Fields(cmdline)
+	if len(args) < 2 || len(args) > 3 {
+		return fmt.Errorf("Usage: .download REMOTE_PATH [LOCAL_PATH]")
+	}
+	carver, ok := s.API.(models.FileCarver)
+	if !ok {
+		return fmt.Errorf("The configured API does not support file carving")
+	}
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return fmt.Errorf("No host is currently connected: %s", err)
+	}
+
+	remotePath := resolveRemotePath(host, args[1])
+
+	ctx, cancel := s.QueryContext()
+	defer cancel()
+	fmt.Fprintf(s.Out, "Carving %s, waiting for the host to upload it...\n", remotePath)
+	archive, err := carve.Download(ctx, carver, host.UUID, remotePath, func(status models.CarveStatus) {
+		fmt.Fprintf(s.Out, "\rReceived %d/%d blocks", status.BlocksReceived, status.BlockCount)
+	})
+	fmt.Fprintf(s.Out, "\n")
+	if err != nil {
+		return err
+	}
+
+	_, contents, single, err := carve.ExtractFile(archive)
+	if err != nil {
+		return err
+	}
+	localPath := path.Base(remotePath)
+	if !single {
+		// Globs and directories carve many files, keep the whole archive
+		contents = archive
+		localPath += ".tar"
+	}
+	if len(args) == 3 {
+		localPath = args[2]
+	}
+
+	if err := ioutil.WriteFile(localPath, contents, 0600); err != nil {
+		return fmt.Errorf("Could not write %s: %s", localPath, err)
+	}
+	fmt.Fprintf(s.Out, "Wrote %d bytes to %s (sha256 %x)\n", len(contents), localPath, sha256.Sum256(contents))
+	return nil
+}
+
+func downloadHelp() string {
+	return "Carve a file from the current host and save it locally. " +
+		"Carves of more than one file are saved as a tar archive"
+}
+
+func downloadSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	// Only the remote path can be completed
+	if strings.Count(cmdline, " ") > 1 {
+		return []prompt.Suggest{}
+	}
+	return remotePathSuggest(s, cmdline, false)
+}
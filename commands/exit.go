@@ -2,23 +2,21 @@ package commands
 
 import (
 	"fmt"
-	"os"
 
-	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
+
 	prompt "github.com/c-bata/go-prompt"
 )
 
-func exit(api models.GoQueryAPI, config *config.Config, cmdline string) error {
-	fmt.Printf("Goodbye!\n")
-	os.Exit(0)
-	return errRuntimeError
+func exit(s *session.Session, cmdline string) error {
+	fmt.Fprintf(s.Out, "Goodbye!\n")
+	return session.ErrExit
 }
 
 func exitHelp() string {
-	return "Exit goquery"
+	return "Exit this goquery session"
 }
 
-func exitSuggest(cmdline string) []prompt.Suggest {
+func exitSuggest(s *session.Session, cmdline string) []prompt.Suggest {
 	return []prompt.Suggest{}
 }
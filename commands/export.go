@@ -0,0 +1,89 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/config"
+	"github.com/AbGuthrie/goquery/v2/session"
+	"github.com/AbGuthrie/goquery/v2/utils"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+// extensionModes maps export file extensions to the print mode they imply
+var extensionModes = map[string]config.PrintModeEnum{
+	".csv":    config.PrintCSV,
+	".json":   config.PrintJSON,
+	".ndjson": config.PrintNDJSON,
+	".tsv":    config.PrintTSV,
+}
+
+func export(s *session.Session, cmdline string) error {
+	args := strings.Fields(cmdline)
+	if len(args) == 1 {
+		return fmt.Errorf("A file path to export to must be provided")
+	}
+	args = args[1:]
+
+	mode := s.Config.PrintMode
+	explicitMode := false
+	if args[0] == "--mode" {
+		if len(args) < 3 {
+			return fmt.Errorf("--mode flag requires arguments: MODE FILE")
+		}
+		requestedMode, ok := validModes[args[1]]
+		if !ok {
+			return fmt.Errorf("%s is not a valid print mode", args[1])
+		}
+		mode = requestedMode
+		explicitMode = true
+		args = args[2:]
+	}
+	if len(args) != 1 {
+		return fmt.Errorf("Exactly one file path must be provided")
+	}
+	path := args[0]
+
+	// Without an explicit mode infer one from the extension, otherwise keep the current mode
+	if extensionMode, ok := extensionModes[strings.ToLower(filepath.Ext(path))]; ok && !explicitMode {
+		mode = extensionMode
+	}
+
+	if s.LastResults == nil {
+		return fmt.Errorf("There are no results to export yet")
+	}
+
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("Could not create export file: %s", err)
+	}
+	defer file.Close()
+
+	if err := utils.FprintQueryResults(file, s.LastResults, mode); err != nil {
+		return fmt.Errorf("Could not write export file: %s", err)
+	}
+
+	fmt.Fprintf(s.Out, "Exported %d row(s) to %s as %s\n", len(s.LastResults), path, mode)
+	return nil
+}
+
+func exportHelp() string {
+	return "Write the last result set to a local file. " +
+		"The format is taken from --mode MODE, the file extension, or the current print mode"
+}
+
+func exportSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	args := strings.Split(cmdline, " ")
+	if len(args) == 2 {
+		return []prompt.Suggest{
+			{Text: "--mode", Description: "Use this flag to choose the export format"},
+		}
+	}
+	if len(args) == 3 && args[1] == "--mode" {
+		return changeModeSuggest(s, cmdline)
+	}
+	return []prompt.Suggest{}
+}
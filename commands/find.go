@@ -0,0 +1,175 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+// defaultFindLimit caps the number of files .find returns unless -limit is given, a
+// recursive search of a large tree can otherwise return the whole filesystem
+const defaultFindLimit = 1000
+
+var findTemplate = "select path, size, datetime(mtime, 'unixepoch') as mtime, mode from file where %s order by path limit %d"
+
+var findTypes = map[string]string{
+	"f": "regular",
+	"d": "directory",
+	"l": "symlink",
+}
+
+var findSizeUnits = map[byte]int64{
+	'c': 1,
+	'k': 1024,
+	'M': 1024 * 1024,
+	'G': 1024 * 1024 * 1024,
+}
+
+func find(s *session.Session, cmdline string) error {
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return fmt.Errorf("No host is currently connected: %s", err)
+	}
+
+	args := strings.Fields(cmdline)[1:]
+	findDir := "."
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		findDir = args[0]
+		args = args[1:]
+	}
+	findDir = resolveRemotePath(host, findDir)
+	if findDir[len(findDir)-1] != '/' {
+		findDir += "/"
+	}
+
+	// %% makes osquery walk every directory below findDir
+	constraints := []string{"path like " + sqlString(findDir+"%%")}
+	limit := defaultFindLimit
+	for len(args) > 0 {
+		if len(args) < 2 {
+			return fmt.Errorf("%s requires a value", args[0])
+		}
+		flag, value := args[0], args[1]
+		args = args[2:]
+
+		switch flag {
+		case "-name":
+			constraints = append(constraints, "filename glob "+sqlString(value))
+		case "-type":
+			fileType, ok := findTypes[value]
+			if !ok {
+				return fmt.Errorf("Invalid type %s, must be f, d or l", value)
+			}
+			constraints = append(constraints, "type = "+sqlString(fileType))
+		case "-mtime":
+			constraint, err := findMtimeConstraint(value)
+			if err != nil {
+				return err
+			}
+			constraints = append(constraints, constraint)
+		case "-size":
+			constraint, err := findSizeConstraint(value)
+			if err != nil {
+				return err
+			}
+			constraints = append(constraints, constraint)
+		case "-limit":
+			limit, err = strconv.Atoi(value)
+			if err != nil || limit <= 0 {
+				return fmt.Errorf("Invalid limit: %s", value)
+			}
+		default:
+			return fmt.Errorf("Unknown option: %s", flag)
+		}
+	}
+
+	findQuery := fmt.Sprintf(findTemplate, strings.Join(constraints, " and "), limit)
+	results, err := s.ScheduleQueryAndWait(host.UUID, findQuery)
+	if err != nil {
+		return err
+	}
+
+	s.PrintResults(results)
+	if len(results) == limit {
+		fmt.Fprintf(s.Out, "Stopped after %d results, use -limit to see more\n", limit)
+	}
+	return nil
+}
+
+// splitFindComparison splits the +/- prefix used by -mtime and -size from the number
+func splitFindComparison(value string) (string, string) {
+	if strings.HasPrefix(value, "+") || strings.HasPrefix(value, "-") {
+		return value[:1], value[1:]
+	}
+	return "", value
+}
+
+// findMtimeConstraint matches files modified less than (-N), more than (+N) or
+// exactly (N) days ago, measured by the host's clock
+func findMtimeConstraint(value string) (string, error) {
+	comparison, number := splitFindComparison(value)
+	days, err := strconv.Atoi(number)
+	if err != nil || days < 0 {
+		return "", fmt.Errorf("Invalid mtime: %s", value)
+	}
+	age := "(strftime('%s', 'now') - mtime)"
+	switch comparison {
+	case "-":
+		return fmt.Sprintf("%s < %d", age, days*86400), nil
+	case "+":
+		return fmt.Sprintf("%s > %d", age, (days+1)*86400), nil
+	}
+	return fmt.Sprintf("%s between %d and %d", age, days*86400, (days+1)*86400-1), nil
+}
+
+// findSizeConstraint matches files smaller than (-N), larger than (+N) or exactly (N)
+// bytes, N may end in c, k, M or G
+func findSizeConstraint(value string) (string, error) {
+	comparison, number := splitFindComparison(value)
+	multiplier := int64(1)
+	if len(number) > 0 {
+		if unit, ok := findSizeUnits[number[len(number)-1]]; ok {
+			multiplier = unit
+			number = number[:len(number)-1]
+		}
+	}
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil || size < 0 {
+		return "", fmt.Errorf("Invalid size: %s", value)
+	}
+	size *= multiplier
+	switch comparison {
+	case "-":
+		return fmt.Sprintf("size < %d", size), nil
+	case "+":
+		return fmt.Sprintf("size > %d", size), nil
+	}
+	return fmt.Sprintf("size = %d", size), nil
+}
+
+func findHelp() string {
+	return "Recursively search for files below a directory on the current host. " +
+		"Filter with -name GLOB, -type f|d|l, -mtime [+-]DAYS, -size [+-]BYTES[kMG] and -limit N"
+}
+
+func findSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	args := strings.Split(cmdline, " ")
+	if len(args) == 2 && !strings.HasPrefix(args[1], "-") {
+		return remotePathSuggest(s, cmdline, true)
+	}
+	last := args[len(args)-1]
+	if !strings.HasPrefix(last, "-") {
+		return []prompt.Suggest{}
+	}
+	return prompt.FilterHasPrefix([]prompt.Suggest{
+		{Text: "-name", Description: "Match file names against a glob"},
+		{Text: "-type", Description: "f for files, d for directories, l for symlinks"},
+		{Text: "-mtime", Description: "Modified less than (-N), more than (+N) or exactly N days ago"},
+		{Text: "-size", Description: "Smaller than (-N), larger than (+N) or exactly N bytes"},
+		{Text: "-limit", Description: fmt.Sprintf("Return at most N files (default %d)", defaultFindLimit)},
+	}, last, false)
+}
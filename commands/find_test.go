@@ -0,0 +1,90 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/internal/apitest"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestFindQuery(t *testing.T) {
+	age := "(strftime('%s', 'now') - mtime)"
+	tests := []struct {
+		cmdline string
+		where   string
+		limit   string
+	}{
+		{".find", "path like '/etc/%%'", "1000"},
+		{".find /", "path like '/%%'", "1000"},
+		{".find ../var/log", "path like '/var/log/%%'", "1000"},
+		{".find . -name *.conf", "path like '/etc/%%' and filename glob '*.conf'", "1000"},
+		{".find /tmp -type d -limit 5", "path like '/tmp/%%' and type = 'directory'", "5"},
+		{".find /o'brien", "path like '/o''brien/%%'", "1000"},
+		{".find -mtime -2", "path like '/etc/%%' and " + age + " < 172800", "1000"},
+		{".find -mtime +2", "path like '/etc/%%' and " + age + " > 259200", "1000"},
+		{".find -mtime 0", "path like '/etc/%%' and " + age + " between 0 and 86399", "1000"},
+		{".find -size +1k", "path like '/etc/%%' and size > 1024", "1000"},
+		{".find -size -2M", "path like '/etc/%%' and size < 2097152", "1000"},
+		{".find -size 10c", "path like '/etc/%%' and size = 10", "1000"},
+	}
+	for _, test := range tests {
+		api := apitest.New(nil)
+		s, _ := newTestSession(t, api, "h")
+		s.Hosts.SetCurrentHostDirectory("/etc/")
+		find(s, test.cmdline)
+
+		want := "select path, size, datetime(mtime, 'unixepoch') as mtime, mode from file where " +
+			test.where + " order by path limit " + test.limit
+		if scheduled := api.Scheduled(); len(scheduled) != 1 || scheduled[0] != want {
+			t.Errorf("%s scheduled %q, want %q", test.cmdline, scheduled, want)
+		}
+	}
+}
+
+func TestFindRejectsInvalidOptions(t *testing.T) {
+	for _, cmdline := range []string{
+		".find -name",
+		".find -type x",
+		".find -mtime soon",
+		".find -mtime -x",
+		".find -size big",
+		".find -size -1T",
+		".find -limit 0",
+		".find -depth 2",
+	} {
+		api := apitest.New(nil)
+		s, _ := newTestSession(t, api, "h")
+		if err := find(s, cmdline); err == nil {
+			t.Errorf("%s did not fail", cmdline)
+		}
+		if len(api.Scheduled()) != 0 {
+			t.Errorf("%s scheduled %q", cmdline, api.Scheduled())
+		}
+	}
+}
+
+func TestFindReportsTheLimit(t *testing.T) {
+	query := "select path, size, datetime(mtime, 'unixepoch') as mtime, mode from file " +
+		"where path like '/%%' order by path limit 2"
+	api := apitest.New(map[string]models.Rows{
+		query: {
+			{"path": "/a", "size": "1", "mtime": "2020-01-01 00:00:00", "mode": "0644"},
+			{"path": "/b", "size": "1", "mtime": "2020-01-01 00:00:00", "mode": "0644"},
+		},
+	})
+	s, out := newTestSession(t, api, "h")
+	if err := find(s, ".find / -limit 2"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "Stopped after 2 results") {
+		t.Fatalf("output does not mention the limit:\n%s", out.String())
+	}
+}
+
+func TestFindRequiresAHost(t *testing.T) {
+	s, _ := newTestSession(t, apitest.New(nil))
+	if err := find(s, ".find /"); err == nil {
+		t.Fatal("find without a connected host did not fail")
+	}
+}
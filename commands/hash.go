@@ -0,0 +1,44 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+var hashTemplate = "select path, md5, sha1, sha256 from hash where path = %s"
+
+func hash(s *session.Session, cmdline string) error {
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return fmt.Errorf("No host is currently connected: %s", err)
+	}
+
+	args := strings.Fields(cmdline)
+	if len(args) < 2 {
+		return fmt.Errorf("Usage: .hash PATH")
+	}
+	hashPath := resolveRemotePath(host, strings.TrimSpace(cmdline[len(args[0]):]))
+
+	results, err := s.ScheduleQueryAndWait(host.UUID, fmt.Sprintf(hashTemplate, sqlString(hashPath)))
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("No such file: %s", hashPath)
+	}
+
+	s.PrintResults(results)
+	return nil
+}
+
+func hashHelp() string {
+	return "Print the md5, sha1 and sha256 of a file on the current host"
+}
+
+func hashSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	return remotePathSuggest(s, cmdline, false)
+}
@@ -0,0 +1,49 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+const defaultHeadLines = 10
+
+func head(s *session.Session, cmdline string) error {
+	args := strings.Fields(cmdline)
+	lines := defaultHeadLines
+	remotePath := ""
+	if len(args) >= 3 && args[1] == "-n" {
+		var err error
+		lines, err = strconv.Atoi(args[2])
+		if err != nil || lines < 0 {
+			return fmt.Errorf("Invalid line count: %s", args[2])
+		}
+		remotePath = strings.TrimSpace(cmdline[strings.Index(cmdline, args[2])+len(args[2]):])
+	} else if len(args) >= 2 {
+		remotePath = strings.TrimSpace(cmdline[len(args[0]):])
+	}
+	if len(remotePath) == 0 {
+		return fmt.Errorf("Usage: .head [-n LINES] PATH")
+	}
+	return printRemoteFile(s, remotePath, lines)
+}
+
+func headHelp() string {
+	return "Print the first lines of a file on the current host (10 unless -n is given)"
+}
+
+func headSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	args := strings.Split(cmdline, " ")
+	if len(args) > 1 && args[1] == "-n" {
+		// Complete the path as if the line count wasn't there
+		if len(args) <= 3 {
+			return []prompt.Suggest{}
+		}
+		return remotePathSuggest(s, args[0]+" "+strings.Join(args[3:], " "), false)
+	}
+	return remotePathSuggest(s, cmdline, false)
+}
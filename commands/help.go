@@ -3,16 +3,14 @@ package commands
 import (
 	"sort"
 
-	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/models"
-	"github.com/AbGuthrie/goquery/v2/utils"
+	"github.com/AbGuthrie/goquery/v2/session"
 
 	prompt "github.com/c-bata/go-prompt"
 )
 
-func help(api models.GoQueryAPI, config *config.Config, cmdline string) error {
+func help(s *session.Session, cmdline string) error {
 	commandNames := make([]string, 0)
-	for k, _ := range CommandMap {
+	for k := range s.Commands {
 		commandNames = append(commandNames, k)
 	}
 
@@ -22,11 +20,11 @@ func help(api models.GoQueryAPI, config *config.Config, cmdline string) error {
 	for _, commandName := range commandNames {
 		helpRows = append(helpRows, map[string]string{
 			"command":     commandName,
-			"description": CommandMap[commandName].Help(),
+			"description": s.Commands[commandName].Help(),
 		})
 	}
 
-	utils.PrettyPrintQueryResults(helpRows, config.PrintMode)
+	s.PrintRows(helpRows)
 	return nil
 }
 
@@ -34,6 +32,6 @@ func helpHelp() string {
 	return "Show the help strings for all goquery commands"
 }
 
-func helpSuggest(cmdline string) []prompt.Suggest {
+func helpSuggest(s *session.Session, cmdline string) []prompt.Suggest {
 	return []prompt.Suggest{}
 }
@@ -0,0 +1,20 @@
+package commands
+
+import (
+	"github.com/AbGuthrie/goquery/v2/hosts"
+)
+
+// hostHasTable reports whether table is available on host. Hosts whose table list
+// couldn't be fetched on connect are assumed to have every table.
+func hostHasTable(host hosts.Host, table string) bool {
+	return len(host.Tables) == 0 || containsString(host.Tables, table)
+}
+
+func containsString(values []string, value string) bool {
+	for _, candidate := range values {
+		if candidate == value {
+			return true
+		}
+	}
+	return false
+}
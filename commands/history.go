@@ -4,37 +4,97 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/hosts"
-	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
 
 	prompt "github.com/c-bata/go-prompt"
 )
 
-func history(api models.GoQueryAPI, config *config.Config, cmdline string) error {
-	args := strings.Split(cmdline, " ") // Separate command and arguments
-	if len(args) > 1 {
-		return fmt.Errorf("This command takes no parameters")
+func history(s *session.Session, cmdline string) error {
+	args := strings.Fields(cmdline)[1:]
+	uuid := ""
+	search := ""
+	for len(args) > 0 {
+		if len(args) < 2 || (args[0] != "--host" && args[0] != "--search") {
+			return fmt.Errorf("Usage: .history [--host UUID] [--search TEXT]")
+		}
+		if args[0] == "--host" {
+			uuid = args[1]
+			args = args[2:]
+			continue
+		}
+		// The search text is the rest of the line
+		search = strings.ToLower(strings.Join(args[1:], " "))
+		args = nil
 	}
 
-	host, err := hosts.GetCurrentHost()
+	if uuid == "" {
+		host, err := s.Hosts.GetCurrentHost()
+		if err != nil {
+			return err
+		}
+		uuid = host.UUID
+	}
+
+	// Without a store only this session's queries are known
+	if s.Store == nil {
+		host, err := s.Hosts.GetHost(uuid)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(s.Out, "Query Name : Query\n")
+		for _, query := range host.QueryHistory {
+			if strings.Contains(strings.ToLower(query.Name+" "+query.SQL), search) {
+				fmt.Fprintf(s.Out, "%s: %s\n", query.Name, query.SQL)
+			}
+		}
+		return nil
+	}
 
+	records, err := s.Store.History(uuid)
 	if err != nil {
 		return err
 	}
-
-	fmt.Printf("Query Name : Query\n")
-	for _, query := range host.QueryHistory {
-		fmt.Printf("%s: %s\n", query.Name, query.SQL)
+	historyRows := make([]map[string]string, 0, len(records))
+	for _, record := range records {
+		if !strings.Contains(strings.ToLower(record.Name+" "+record.SQL), search) {
+			continue
+		}
+		historyRows = append(historyRows, map[string]string{
+			"time":   record.Time.Format("2006-01-02 15:04:05"),
+			"name":   record.Name,
+			"status": string(record.Status),
+			"rows":   fmt.Sprintf("%d", record.RowCount),
+			"query":  record.SQL,
+		})
 	}
-
+	if len(historyRows) == 0 {
+		fmt.Fprintf(s.Out, "No queries found\n")
+		return nil
+	}
+	s.PrintRows(historyRows)
 	return nil
 }
 
 func historyHelp() string {
-	return "Print the current host's query history from the current session"
+	return "Print the query history of the current host, including previous sessions. " +
+		"--host UUID shows another host and --search TEXT filters by query name or SQL"
 }
 
-func historySuggest(cmdline string) []prompt.Suggest {
+func historySuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	args := strings.Split(cmdline, " ")
+	if len(args) > 2 && args[len(args)-2] == "--host" && s.Store != nil {
+		prompts := []prompt.Suggest{}
+		uuids, _ := s.Store.Hosts()
+		for _, uuid := range uuids {
+			prompts = append(prompts, prompt.Suggest{Text: uuid})
+		}
+		return prompt.FilterHasPrefix(prompts, args[len(args)-1], false)
+	}
+	if len(args) >= 2 && strings.HasPrefix(args[len(args)-1], "-") {
+		return prompt.FilterHasPrefix([]prompt.Suggest{
+			{Text: "--host", Description: "Show the history of another host"},
+			{Text: "--search", Description: "Only show queries whose name or SQL contains the text"},
+		}, args[len(args)-1], false)
+	}
 	return []prompt.Suggest{}
 }
@@ -0,0 +1,63 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/jobs"
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+func listJobs(s *session.Session, cmdline string) error {
+	args := strings.Split(cmdline, " ") // Separate command and arguments
+	if len(args) > 1 {
+		return fmt.Errorf("This command takes no parameters")
+	}
+
+	jobRows := make([]map[string]string, 0)
+	for _, job := range s.Jobs.Jobs() {
+		errorText := ""
+		if job.Err != nil {
+			errorText = job.Err.Error()
+		}
+		jobRows = append(jobRows, map[string]string{
+			"name":     job.Name,
+			"host":     job.Host.ComputerName,
+			"status":   job.Status,
+			"started":  job.Started.Format(time.RFC3339),
+			"duration": job.Duration().Round(time.Second).String(),
+			"rows":     fmt.Sprintf("%d", len(job.Rows)),
+			"query":    job.SQL,
+			"error":    errorText,
+		})
+	}
+
+	s.PrintRows(jobRows)
+	return nil
+}
+
+func listJobsHelp() string {
+	return "List every scheduled query with its status and duration"
+}
+
+func listJobsSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	return []prompt.Suggest{}
+}
+
+// jobSuggest suggests the name of every job matching filter, described by its host and query
+func jobSuggest(s *session.Session, filter func(jobs.Job) bool) []prompt.Suggest {
+	prompts := []prompt.Suggest{}
+	for _, job := range s.Jobs.Jobs() {
+		if !filter(job) {
+			continue
+		}
+		prompts = append(prompts, prompt.Suggest{
+			Text:        job.Name,
+			Description: fmt.Sprintf("[%s] %s: %s", job.Status, job.Host.ComputerName, job.SQL),
+		})
+	}
+	return prompts
+}
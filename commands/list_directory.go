@@ -2,19 +2,15 @@ package commands
 
 import (
 	"fmt"
-	"path/filepath"
 	"strings"
 
-	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/hosts"
-	"github.com/AbGuthrie/goquery/v2/models"
-	"github.com/AbGuthrie/goquery/v2/utils"
+	"github.com/AbGuthrie/goquery/v2/session"
 
 	prompt "github.com/c-bata/go-prompt"
 )
 
-func listDirectory(api models.GoQueryAPI, config *config.Config, cmdline string) error {
-	host, err := hosts.GetCurrentHost()
+func listDirectory(s *session.Session, cmdline string) error {
+	host, err := s.Hosts.GetCurrentHost()
 	if err != nil {
 		return fmt.Errorf("No host is currently connected: %s", err)
 	}
@@ -28,31 +24,30 @@ func listDirectory(api models.GoQueryAPI, config *config.Config, cmdline string)
 		}
 	}
 
-	if lsDir[0] != '/' {
-		lsDir = host.CurrentDirectory + lsDir
-	}
-	lsDir = filepath.Clean(lsDir)
-
+	lsPath := resolveRemotePath(host, lsDir)
+	lsDir = lsPath
 	// All directory changes must end with a forward slash
 	if lsDir[len(lsDir)-1] != '/' {
 		lsDir += "/"
 	}
 
-	listQuery := fmt.Sprintf("select * from file where directory = '%s'", lsDir)
-	results, err := utils.ScheduleQueryAndWait(api, host.UUID, listQuery)
+	// A file is listed on its own, the second select finds nothing for a directory
+	listQuery := fmt.Sprintf("select * from file where directory = %s union all "+
+		"select * from file where path = %s and type != 'directory'", sqlString(lsDir), sqlString(lsPath))
+	results, err := s.ScheduleQueryAndWait(host.UUID, listQuery)
 
 	if err != nil {
 		return err
 	}
 
-	utils.PrettyPrintQueryResults(results, config.PrintMode)
+	s.PrintResults(results)
 	return nil
 }
 
 func listDirectoryHelp() string {
-	return "List the files in the current directory on the remote host"
+	return "List the files in the current directory, or a given directory or file, on the remote host"
 }
 
-func listDirectorySuggest(cmdline string) []prompt.Suggest {
-	return []prompt.Suggest{}
+func listDirectorySuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	return remotePathSuggest(s, cmdline, false)
 }
@@ -0,0 +1,50 @@
+package commands
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/internal/apitest"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestListDirectorySuggestCompletesFiles(t *testing.T) {
+	api := apitest.New(map[string]models.Rows{
+		"select filename, type from file where directory = '/etc/'": {
+			{"filename": "hosts", "type": "regular"},
+			{"filename": "ssh", "type": "directory"},
+		},
+	})
+	s, _ := newTestSession(t, api, "h")
+	s.Hosts.SetCurrentHostDirectory("/etc/")
+
+	// The first call starts fetching the listing in the background
+	listDirectorySuggest(s, "ls ")
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		got := make([]string, 0)
+		for _, suggestion := range listDirectorySuggest(s, "ls ") {
+			got = append(got, suggestion.Text)
+		}
+		if reflect.DeepEqual(got, []string{"hosts", "ssh/"}) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("ls suggestions = %v", got)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestListDirectoryQuery(t *testing.T) {
+	api := apitest.New(nil)
+	s, _ := newTestSession(t, api, "h")
+	s.Hosts.SetCurrentHostDirectory("/etc/")
+	listDirectory(s, "ls ../etc/hosts")
+	want := "select * from file where directory = '/etc/hosts/' union all " +
+		"select * from file where path = '/etc/hosts' and type != 'directory'"
+	if len(api.Scheduled()) != 1 || api.Scheduled()[0] != want {
+		t.Fatalf("scheduled %q, want %q", api.Scheduled(), want)
+	}
+}
@@ -6,18 +6,21 @@ import (
 	"strings"
 
 	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
 
 	prompt "github.com/c-bata/go-prompt"
 )
 
 var validModes = map[string]config.PrintModeEnum{
+	"csv":    config.PrintCSV,
 	"json":   config.PrintJSON,
 	"line":   config.PrintLine,
+	"ndjson": config.PrintNDJSON,
 	"pretty": config.PrintPretty,
+	"tsv":    config.PrintTSV,
 }
 
-func changeMode(api models.GoQueryAPI, config *config.Config, cmdline string) error {
+func changeMode(s *session.Session, cmdline string) error {
 	args := strings.Split(cmdline, " ") // Separate command and arguments
 	if len(args) == 1 {
 		return fmt.Errorf("Mode parameter required")
@@ -30,8 +33,8 @@ func changeMode(api models.GoQueryAPI, config *config.Config, cmdline string) er
 		return fmt.Errorf("%s is not a valid print mode", modeArg)
 	}
 
-	config.SetPrintMode(mode)
-	fmt.Printf("Print mode set to '%s'.\n", modeArg)
+	s.Config.SetPrintMode(mode)
+	fmt.Fprintf(s.Out, "Print mode set to '%s'.\n", modeArg)
 
 	return nil
 }
@@ -45,7 +48,7 @@ func changeModeHelp() string {
 	return fmt.Sprintf("Change print mode (%s)", strings.Join(modeNames, ", "))
 }
 
-func changeModeSuggest(cmdline string) []prompt.Suggest {
+func changeModeSuggest(s *session.Session, cmdline string) []prompt.Suggest {
 	prompts := []prompt.Suggest{}
 
 	modeNames := make([]string, 0)
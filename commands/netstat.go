@@ -0,0 +1,146 @@
+package commands
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+var listeningPortsTemplate = "select s.pid, %s as process, s.protocol, s.family, s.address as local_address, s.port as local_port, " +
+	"'' as remote_address, '' as remote_port, '' as state from listening_ports s %s"
+
+var openSocketsTemplate = "select s.pid, %s as process, s.protocol, s.family, s.local_address, s.local_port, " +
+	"s.remote_address, s.remote_port, s.state from process_open_sockets s %s"
+
+var socketProcessJoin = "left join processes p on s.pid = p.pid"
+
+// socketFamilies names the address families osquery reports, AF_INET6 differs by platform
+var socketFamilies = map[string]string{
+	"1":  "unix",
+	"2":  "inet",
+	"10": "inet6",
+	"23": "inet6",
+	"30": "inet6",
+}
+
+var socketProtocols = map[string]string{
+	"1":  "icmp",
+	"6":  "tcp",
+	"17": "udp",
+	"58": "icmp6",
+}
+
+func netstat(s *session.Session, cmdline string) error {
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return fmt.Errorf("No host is currently connected: %s", err)
+	}
+
+	args := strings.Fields(cmdline)[1:]
+	listening := false
+	pid := ""
+	for len(args) > 0 {
+		switch args[0] {
+		case "-l":
+			listening = true
+			args = args[1:]
+		case "-p":
+			if len(args) < 2 {
+				return fmt.Errorf("-p requires a pid")
+			}
+			if _, err := strconv.Atoi(args[1]); err != nil {
+				return fmt.Errorf("Invalid pid: %s", args[1])
+			}
+			pid = args[1]
+			args = args[2:]
+		default:
+			return fmt.Errorf("Usage: .netstat [-l] [-p PID]")
+		}
+	}
+
+	table, template := "process_open_sockets", openSocketsTemplate
+	if listening {
+		table, template = "listening_ports", listeningPortsTemplate
+	}
+	if !hostHasTable(host, table) {
+		return fmt.Errorf("The %s table is not available on this %s host", table, host.Platform)
+	}
+	processColumn, join := "p.name", socketProcessJoin
+	if !hostHasTable(host, "processes") {
+		fmt.Fprintf(s.Out, "Warning: the processes table is not available on this %s host, process names will be missing\n", host.Platform)
+		processColumn, join = "''", ""
+	}
+
+	query := fmt.Sprintf(template, processColumn, join)
+	if pid != "" {
+		query += " where s.pid = " + pid
+	}
+	results, err := s.ScheduleQueryAndWait(host.UUID, query)
+	if err != nil {
+		return err
+	}
+
+	connections := make([]map[string]string, 0, len(results))
+	for _, row := range results {
+		// Unix sockets have no addresses to show
+		if row["family"] == "1" {
+			continue
+		}
+		state := row["state"]
+		if listening && row["protocol"] == "6" {
+			state = "LISTEN"
+		}
+		connections = append(connections, map[string]string{
+			"proto":   socketProtocol(row["protocol"], row["family"]),
+			"local":   socketAddress(row["local_address"], row["local_port"]),
+			"remote":  socketAddress(row["remote_address"], row["remote_port"]),
+			"state":   state,
+			"pid":     row["pid"],
+			"process": row["process"],
+		})
+	}
+	s.PrintResults(connections)
+	return nil
+}
+
+// socketProtocol names a protocol the way netstat does, tcp6 for TCP over IPv6
+func socketProtocol(protocol, family string) string {
+	name, ok := socketProtocols[protocol]
+	if !ok {
+		name = protocol
+	}
+	if socketFamilies[family] == "inet6" && !strings.HasSuffix(name, "6") {
+		name += "6"
+	}
+	return name
+}
+
+func socketAddress(address, port string) string {
+	if address == "" {
+		return ""
+	}
+	if strings.Contains(address, ":") {
+		address = "[" + address + "]"
+	}
+	return address + ":" + port
+}
+
+func netstatHelp() string {
+	return "List the open sockets on the current host and the processes that own them. " +
+		"-l shows only listening ports and -p PID only the sockets of one process"
+}
+
+func netstatSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	args := strings.Split(cmdline, " ")
+	if len(args) < 2 || !strings.HasPrefix(args[len(args)-1], "-") {
+		return []prompt.Suggest{}
+	}
+	return prompt.FilterHasPrefix([]prompt.Suggest{
+		{Text: "-l", Description: "Only show listening ports"},
+		{Text: "-p", Description: "Only show the sockets of a pid"},
+	}, args[len(args)-1], false)
+}
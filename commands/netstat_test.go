@@ -0,0 +1,100 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/internal/apitest"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestNetstatQuery(t *testing.T) {
+	tests := []struct {
+		cmdline string
+		tables  []string
+		want    string
+	}{
+		{".netstat", nil, fmt.Sprintf(openSocketsTemplate, "p.name", socketProcessJoin)},
+		{".netstat -l", nil, fmt.Sprintf(listeningPortsTemplate, "p.name", socketProcessJoin)},
+		{".netstat -p 42", nil, fmt.Sprintf(openSocketsTemplate, "p.name", socketProcessJoin) + " where s.pid = 42"},
+		{".netstat -l -p 42", nil, fmt.Sprintf(listeningPortsTemplate, "p.name", socketProcessJoin) + " where s.pid = 42"},
+		{".netstat", []string{"process_open_sockets"}, fmt.Sprintf(openSocketsTemplate, "''", "")},
+	}
+	for _, test := range tests {
+		api := apitest.New(nil)
+		s, _ := newTestSession(t, api, "h")
+		if test.tables != nil {
+			s.Hosts.SetHostTables("h", test.tables)
+		}
+		netstat(s, test.cmdline)
+		if scheduled := api.Scheduled(); len(scheduled) != 1 || scheduled[0] != test.want {
+			t.Errorf("%s scheduled %q, want %q", test.cmdline, scheduled, test.want)
+		}
+	}
+}
+
+func TestNetstatRejectsInvalidOptions(t *testing.T) {
+	for _, cmdline := range []string{".netstat -p", ".netstat -p init", ".netstat -a"} {
+		api := apitest.New(nil)
+		s, _ := newTestSession(t, api, "h")
+		if err := netstat(s, cmdline); err == nil {
+			t.Errorf("%s did not fail", cmdline)
+		}
+		if len(api.Scheduled()) != 0 {
+			t.Errorf("%s scheduled %q", cmdline, api.Scheduled())
+		}
+	}
+}
+
+func TestNetstatRequiresTheSocketTable(t *testing.T) {
+	api := apitest.New(nil)
+	s, _ := newTestSession(t, api, "h")
+	s.Hosts.SetHostTables("h", []string{"processes"})
+	err := netstat(s, ".netstat -l")
+	if err == nil || !strings.Contains(err.Error(), "listening_ports") {
+		t.Fatalf("netstat without listening_ports returned %v", err)
+	}
+	if len(api.Scheduled()) != 0 {
+		t.Fatalf("scheduled %q", api.Scheduled())
+	}
+}
+
+func TestNetstatFormatsConnections(t *testing.T) {
+	query := fmt.Sprintf(listeningPortsTemplate, "p.name", socketProcessJoin)
+	api := apitest.New(map[string]models.Rows{
+		query: {
+			{"pid": "10", "process": "sshd", "protocol": "6", "family": "2", "local_address": "0.0.0.0", "local_port": "22"},
+			{"pid": "11", "process": "named", "protocol": "17", "family": "10", "local_address": "::1", "local_port": "53"},
+			{"pid": "12", "process": "dbus", "protocol": "0", "family": "1", "local_address": "/run/dbus", "local_port": "0"},
+		},
+	})
+	s, out := newTestSession(t, api, "h")
+	if err := netstat(s, ".netstat -l"); err != nil {
+		t.Fatal(err)
+	}
+	output := out.String()
+	for _, want := range []string{"0.0.0.0:22", "LISTEN", "sshd", "udp6", "[::1]:53"} {
+		if !strings.Contains(output, want) {
+			t.Errorf("output does not contain %q:\n%s", want, output)
+		}
+	}
+	if strings.Contains(output, "/run/dbus") {
+		t.Errorf("output contains a unix socket:\n%s", output)
+	}
+}
+
+func TestSocketProtocol(t *testing.T) {
+	tests := []struct{ protocol, family, want string }{
+		{"6", "2", "tcp"},
+		{"6", "10", "tcp6"},
+		{"17", "30", "udp6"},
+		{"58", "10", "icmp6"},
+		{"132", "2", "132"},
+	}
+	for _, test := range tests {
+		if got := socketProtocol(test.protocol, test.family); got != test.want {
+			t.Errorf("socketProtocol(%s, %s) = %s, want %s", test.protocol, test.family, got, test.want)
+		}
+	}
+}
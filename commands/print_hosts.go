@@ -4,15 +4,12 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/hosts"
-	"github.com/AbGuthrie/goquery/v2/models"
-	"github.com/AbGuthrie/goquery/v2/utils"
+	"github.com/AbGuthrie/goquery/v2/session"
 
 	prompt "github.com/c-bata/go-prompt"
 )
 
-func printHosts(api models.GoQueryAPI, config *config.Config, cmdline string) error {
+func printHosts(s *session.Session, cmdline string) error {
 	args := strings.Split(cmdline, " ") // Separate command and arguments
 	if len(args) > 1 {
 		return fmt.Errorf("This command takes no parameters")
@@ -20,7 +17,7 @@ func printHosts(api models.GoQueryAPI, config *config.Config, cmdline string) er
 
 	hostRows := make([]map[string]string, 0)
 
-	for _, host := range hosts.GetCurrentHosts() {
+	for _, host := range s.Hosts.GetCurrentHosts() {
 		hostRows = append(hostRows, map[string]string{
 			"UUID":              host.UUID,
 			"Name":              host.ComputerName,
@@ -31,7 +28,7 @@ func printHosts(api models.GoQueryAPI, config *config.Config, cmdline string) er
 		})
 	}
 
-	utils.PrettyPrintQueryResults(hostRows, config.PrintMode)
+	s.PrintRows(hostRows)
 
 	return nil
 }
@@ -40,6 +37,6 @@ func printHostsHelp() string {
 	return "Prints out all connected hosts"
 }
 
-func printHostsSuggest(cmdline string) []prompt.Suggest {
+func printHostsSuggest(s *session.Session, cmdline string) []prompt.Suggest {
 	return []prompt.Suggest{}
 }
@@ -0,0 +1,56 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+var processesQuery = "select p.pid, p.parent as ppid, coalesce(u.username, p.uid) as user, p.name, p.cmdline, p.path, p.on_disk " +
+	"from processes p left join users u on p.uid = u.uid"
+
+var processFilterTemplate = " where p.name like %[1]s or p.cmdline like %[1]s or p.path like %[1]s"
+
+// processColumns are the columns of processesQuery that .ps shows
+var processColumns = []string{"pid", "ppid", "user", "cmdline", "path"}
+
+func ps(s *session.Session, cmdline string) error {
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return fmt.Errorf("No host is currently connected: %s", err)
+	}
+
+	query := processesQuery
+	args := strings.Fields(cmdline)
+	if len(args) > 1 {
+		filter := strings.TrimSpace(cmdline[len(args[0]):])
+		query += fmt.Sprintf(processFilterTemplate, sqlString("%"+filter+"%"))
+	}
+	query += " order by p.pid"
+
+	results, err := s.ScheduleQueryAndWait(host.UUID, query)
+	if err != nil {
+		return err
+	}
+
+	for i, row := range results {
+		process := make(map[string]string, len(processColumns))
+		for _, column := range processColumns {
+			process[column] = row[column]
+		}
+		results[i] = process
+	}
+	s.PrintResults(results)
+	return nil
+}
+
+func psHelp() string {
+	return "List the processes on the current host, optionally only those whose name, command line or path contains a filter"
+}
+
+func psSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	return []prompt.Suggest{}
+}
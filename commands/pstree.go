@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
+	"github.com/AbGuthrie/goquery/v2/utils"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+func pstree(s *session.Session, cmdline string) error {
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return fmt.Errorf("No host is currently connected: %s", err)
+	}
+
+	args := strings.Fields(cmdline)
+	markDeleted := false
+	if len(args) == 2 && (args[1] == "-d" || args[1] == "--deleted") {
+		markDeleted = true
+	} else if len(args) > 1 {
+		return fmt.Errorf("Usage: .pstree [--deleted]")
+	}
+
+	results, err := s.ScheduleQueryAndWait(host.UUID, processesQuery)
+	if err != nil {
+		return err
+	}
+
+	err = printProcessTree(s.PagedOut(), results, markDeleted)
+	if err == utils.ErrPagerQuit {
+		return nil
+	}
+	return err
+}
+
+// printProcessTree writes processes as a tree of parents and their children ordered by
+// pid. Processes whose parent isn't in the list start a tree of their own.
+func printProcessTree(w io.Writer, processes models.Rows, markDeleted bool) error {
+	pids := make(map[string]bool, len(processes))
+	for _, process := range processes {
+		pids[process["pid"]] = true
+	}
+	children := make(map[string][]map[string]string)
+	roots := make([]map[string]string, 0)
+	for _, process := range processes {
+		parent := process["ppid"]
+		if !pids[parent] || parent == process["pid"] {
+			roots = append(roots, process)
+			continue
+		}
+		children[parent] = append(children[parent], process)
+	}
+	sortByPid(roots)
+	for _, siblings := range children {
+		sortByPid(siblings)
+	}
+
+	// Build the tree before printing it so each branch knows which of its children are
+	// drawn. A pid reused while the snapshot was taken can make a loop or list a process
+	// twice, each pid is only placed in the tree once.
+	placed := make(map[string]bool, len(processes))
+	var place func(process map[string]string) *processNode
+	place = func(process map[string]string) *processNode {
+		node := &processNode{process: process}
+		placed[process["pid"]] = true
+		for _, child := range children[process["pid"]] {
+			if !placed[child["pid"]] {
+				node.children = append(node.children, place(child))
+			}
+		}
+		return node
+	}
+	trees := make([]*processNode, 0, len(roots))
+	for _, root := range roots {
+		if !placed[root["pid"]] {
+			trees = append(trees, place(root))
+		}
+	}
+	// Anything left is part of a parent loop with no root, print it from where it starts
+	sortByPid(processes)
+	for _, process := range processes {
+		if !placed[process["pid"]] {
+			trees = append(trees, place(process))
+		}
+	}
+
+	for _, tree := range trees {
+		if err := printProcessNode(w, tree, markDeleted, "", "", ""); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// processNode is a process and the children drawn below it in a process tree
+type processNode struct {
+	process  map[string]string
+	children []*processNode
+}
+
+func printProcessNode(w io.Writer, node *processNode, markDeleted bool, indent string, branch string, childIndent string) error {
+	process := node.process
+	line := fmt.Sprintf("%s%s%s %s (%s) %s", indent, branch, process["pid"], process["name"], process["user"], process["cmdline"])
+	if markDeleted && process["on_disk"] == "0" {
+		line += " [deleted: " + process["path"] + "]"
+	}
+	if _, err := fmt.Fprintln(w, strings.TrimRight(line, " ")); err != nil {
+		return err
+	}
+
+	for i, child := range node.children {
+		branch, next := "├─ ", "│  "
+		if i == len(node.children)-1 {
+			branch, next = "└─ ", "   "
+		}
+		if err := printProcessNode(w, child, markDeleted, indent+childIndent, branch, next); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func sortByPid(processes []map[string]string) {
+	sort.Slice(processes, func(i, j int) bool {
+		left, _ := strconv.Atoi(processes[i]["pid"])
+		right, _ := strconv.Atoi(processes[j]["pid"])
+		return left < right
+	})
+}
+
+func pstreeHelp() string {
+	return "Show the processes on the current host as a tree, " +
+		"--deleted marks processes whose binary has been deleted from disk"
+}
+
+func pstreeSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	if strings.Count(cmdline, " ") != 1 {
+		return []prompt.Suggest{}
+	}
+	return prompt.FilterHasPrefix([]prompt.Suggest{
+		{Text: "--deleted", Description: "Mark processes whose binary is no longer on disk"},
+	}, cmdline[strings.Index(cmdline, " ")+1:], false)
+}
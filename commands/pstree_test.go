@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestPrintProcessTree(t *testing.T) {
+	tests := []struct {
+		processes models.Rows
+		want      string
+	}{
+		{
+			models.Rows{
+				{"pid": "1", "ppid": "0", "name": "init"},
+				{"pid": "3", "ppid": "1", "name": "b"},
+				{"pid": "2", "ppid": "1", "name": "a"},
+				{"pid": "4", "ppid": "2", "name": "c"},
+			},
+			"1 init ()\n├─ 2 a ()\n│  └─ 4 c ()\n└─ 3 b ()\n",
+		},
+		{
+			// pid 3 was reused while the snapshot was taken, the second row is skipped so
+			// the first is the last child drawn
+			models.Rows{
+				{"pid": "1", "ppid": "0", "name": "init"},
+				{"pid": "2", "ppid": "1", "name": "a"},
+				{"pid": "3", "ppid": "1", "name": "b"},
+				{"pid": "3", "ppid": "1", "name": "reused"},
+			},
+			"1 init ()\n├─ 2 a ()\n└─ 3 b ()\n",
+		},
+		{
+			// 3 is listed under both 1 and 2, it is drawn under 2 and 2 is then the last
+			// child of 1
+			models.Rows{
+				{"pid": "1", "ppid": "0", "name": "init"},
+				{"pid": "2", "ppid": "1", "name": "a"},
+				{"pid": "3", "ppid": "2", "name": "b"},
+				{"pid": "3", "ppid": "1", "name": "reused"},
+			},
+			"1 init ()\n└─ 2 a ()\n   └─ 3 b ()\n",
+		},
+		{
+			// A parent loop without a root starts from its lowest pid
+			models.Rows{
+				{"pid": "5", "ppid": "4", "name": "d"},
+				{"pid": "4", "ppid": "5", "name": "c"},
+			},
+			"4 c ()\n└─ 5 d ()\n",
+		},
+	}
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		if err := printProcessTree(out, test.processes, false); err != nil {
+			t.Fatal(err)
+		}
+		if out.String() != test.want {
+			t.Errorf("unexpected tree:\n%s\nwant:\n%s", out, test.want)
+		}
+	}
+}
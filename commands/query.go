@@ -4,63 +4,79 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/AbGuthrie/goquery/v2/config"
 	"github.com/AbGuthrie/goquery/v2/hosts"
-	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
 	"github.com/AbGuthrie/goquery/v2/utils"
 
 	prompt "github.com/c-bata/go-prompt"
 )
 
-func query(api models.GoQueryAPI, config *config.Config, cmdline string) error {
-	host, err := hosts.GetCurrentHost()
-	if err != nil {
-		return fmt.Errorf("No host is currently connected: %s", err)
-	}
-
+func query(s *session.Session, cmdline string) error {
 	args := strings.Split(cmdline, " ") // Separate command and arguments
 	if len(args) == 1 {
 		return fmt.Errorf("A query to run must be provided")
 	}
 	// TODO This needs to support Unicode/Runes
 	commandStripped := cmdline[strings.Index(cmdline, " ")+1:]
-	results, err := utils.ScheduleQueryAndWait(api, host.UUID, commandStripped)
 
+	targets, commandStripped, fanOut, err := parseHostTargets(s, commandStripped)
 	if err != nil {
 		return err
 	}
+	if len(commandStripped) == 0 {
+		return fmt.Errorf("A query to run must be provided")
+	}
+	if fanOut {
+		return queryHosts(s, targets, commandStripped)
+	}
+
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return fmt.Errorf("No host is currently connected: %s", err)
+	}
+	return s.ScheduleQueryAndPrint(host.UUID, commandStripped)
+}
+
+// queryHosts runs a query on every target host concurrently, prints the merged
+// rows and then a per host status summary
+func queryHosts(s *session.Session, targets []hosts.Host, query string) error {
+	fmt.Fprintf(s.Out, "Running query on %d host(s)...\n", len(targets))
+	hostResults := s.ScheduleQueryOnHostsAndWait(targets, query)
 
-	utils.PrettyPrintQueryResults(results, config.PrintMode)
+	merged := utils.MergeHostResults(hostResults)
+	if len(merged) > 0 {
+		s.PrintResults(merged)
+	}
+	s.PrintRows(utils.HostResultSummary(hostResults))
 
+	for _, result := range hostResults {
+		if result.Err != nil {
+			return fmt.Errorf("Query failed on one or more hosts")
+		}
+	}
 	return nil
 }
 
 func queryHelp() string {
-	return "Schedule a query on a host and wait for results"
+	return "Schedule a query on a host and wait for results. " +
+		"Use --all, --hosts UUID1,UUID2 or --group NAME to run on many connected hosts"
 }
 
-func querySuggest(cmdline string) []prompt.Suggest {
-	parts := strings.Split(cmdline, " ")
-	// The cmdline doesn't have enough components
-	if len(parts) < 2 {
-		return []prompt.Suggest{}
+func querySuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	if targetPrompts := hostTargetSuggest(s, cmdline); len(targetPrompts) > 0 {
+		return targetPrompts
 	}
 
-	// If they've anything other than "from "
-	if parts[len(parts)-2] != "from" {
+	// The cmdline doesn't have enough components
+	argumentStart := strings.Index(cmdline, " ")
+	if argumentStart < 0 {
 		return []prompt.Suggest{}
 	}
 
-	prompts := []prompt.Suggest{}
-
 	// There is no connected host
-	host, err := hosts.GetCurrentHost()
+	host, err := s.Hosts.GetCurrentHost()
 	if err != nil {
-		return prompts
-	}
-	for _, table := range host.Tables {
-		prompts = append(prompts, prompt.Suggest{table, ""})
+		return []prompt.Suggest{}
 	}
-
-	return prompts
+	return sqlSuggest(s, host, cmdline[argumentStart+1:])
 }
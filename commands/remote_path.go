@@ -0,0 +1,68 @@
+package commands
+
+import (
+	"path"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+// resolveRemotePath makes a path typed by the user absolute using the host's current
+// directory, the same way cd and ls do
+func resolveRemotePath(host hosts.Host, remotePath string) string {
+	if len(remotePath) == 0 || remotePath[0] != '/' {
+		remotePath = host.CurrentDirectory + remotePath
+	}
+	return path.Clean(remotePath)
+}
+
+// sqlString quotes value as an SQL string literal so remote paths can be put in queries
+func sqlString(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// remotePathSuggest completes the argument of cmdline as a path on the current host using
+// cached directory listings. Only directories are offered when directoriesOnly is set.
+func remotePathSuggest(s *session.Session, cmdline string, directoriesOnly bool) []prompt.Suggest {
+	prompts := []prompt.Suggest{}
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return prompts
+	}
+	argumentStart := strings.Index(cmdline, " ")
+	if argumentStart < 0 {
+		return prompts
+	}
+	partial := cmdline[argumentStart+1:]
+
+	// Complete the name after the last slash within the directory before it
+	typedDirectory := partial[:strings.LastIndex(partial, "/")+1]
+	directory := typedDirectory
+	if len(directory) == 0 || directory[0] != '/' {
+		directory = host.CurrentDirectory + directory
+	}
+	// Remote paths always use forward slashes whatever the local OS is
+	directory = path.Clean(directory)
+	if directory[len(directory)-1] != '/' {
+		directory += "/"
+	}
+
+	entries, ok := s.CachedDirectory(host.UUID, directory)
+	if !ok {
+		return []prompt.Suggest{{Text: partial, Description: "Fetching " + directory + " from the host..."}}
+	}
+	for _, entry := range entries {
+		if entry.Name == "." || entry.Name == ".." {
+			continue
+		}
+		if entry.IsDirectory {
+			prompts = append(prompts, prompt.Suggest{Text: typedDirectory + entry.Name + "/", Description: "directory"})
+		} else if !directoriesOnly {
+			prompts = append(prompts, prompt.Suggest{Text: typedDirectory + entry.Name, Description: "file"})
+		}
+	}
+	return prompts
+}
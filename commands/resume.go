@@ -1,35 +1,57 @@
 package commands
 
 import (
+	"errors"
 	"fmt"
 	"strings"
 
-	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/jobs"
 	"github.com/AbGuthrie/goquery/v2/models"
-	"github.com/AbGuthrie/goquery/v2/utils"
+	"github.com/AbGuthrie/goquery/v2/session"
 
 	prompt "github.com/c-bata/go-prompt"
 )
 
-func resume(api models.GoQueryAPI, config *config.Config, cmdline string) error {
+func resume(s *session.Session, cmdline string) error {
 	args := strings.Split(cmdline, " ") // Separate command and arguments
 	if len(args) == 1 {
 		return fmt.Errorf("A query name to resume must be provided")
 	}
 	// TODO This needs to support Unicode/Runes
 	commandStripped := cmdline[strings.Index(cmdline, " ")+1:]
-	results, status, err := api.FetchResults(commandStripped)
 
+	// Stored results don't need the backend, so old queries can be viewed offline
+	if s.Store != nil {
+		uuid, record, ok, err := s.Store.Find(commandStripped)
+		if err != nil {
+			return err
+		}
+		if ok && record.Results != nil {
+			fmt.Fprintf(s.Out, "Results from %s stored at %s\n", uuid, record.Time.Format("2006-01-02 15:04:05"))
+			s.PrintResults(record.Results)
+			return nil
+		}
+	}
+
+	ctx, cancel := s.QueryContext()
+	defer cancel()
+	results, status, err := s.ContextAPI().FetchResultsContext(ctx, commandStripped)
+
+	if errors.Is(err, models.ErrQueryNotFound) {
+		return fmt.Errorf("No query named %s was found", commandStripped)
+	}
 	if err != nil {
 		return err
 	}
 
-	if status == "Pending" {
+	if status.Pending() {
 		return fmt.Errorf("Query does not have results available yet")
 	}
+	if err := status.Err(); err != nil {
+		return err
+	}
 
-	utils.PrettyPrintQueryResults(results, config.PrintMode)
+	s.PrintResults(results)
 
 	return nil
 }
@@ -38,15 +60,37 @@ func resumeHelp() string {
 	return "Try to fetch results for a query but don't block if unavailable"
 }
 
-func resumeSuggest(cmdline string) []prompt.Suggest {
-	host, err := hosts.GetCurrentHost()
-	prompts := []prompt.Suggest{}
+func resumeSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	prompts := jobSuggest(s, func(job jobs.Job) bool { return true })
+
+	// Queries run with .query or by other commands are not tracked as jobs
+	host, err := s.Hosts.GetCurrentHost()
 	if err != nil {
 		return prompts
 	}
-
 	for _, query := range host.QueryHistory {
-		prompts = append(prompts, prompt.Suggest{query.Name, query.SQL})
+		if _, err := s.Jobs.Get(query.Name); err == nil {
+			continue
+		}
+		prompts = append(prompts, prompt.Suggest{Text: query.Name, Description: query.SQL})
+	}
+
+	// Then queries from previous sessions, newest first
+	if s.Store == nil {
+		return prompts
+	}
+	records, err := s.Store.History(host.UUID)
+	if err != nil {
+		return prompts
+	}
+	suggested := make(map[string]bool, len(prompts))
+	for _, suggestion := range prompts {
+		suggested[suggestion.Text] = true
+	}
+	for i := len(records) - 1; i >= 0; i-- {
+		if !suggested[records[i].Name] {
+			prompts = append(prompts, prompt.Suggest{Text: records[i].Name, Description: records[i].SQL})
+		}
 	}
 	return prompts
 }
@@ -0,0 +1,26 @@
+package commands
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/internal/apitest"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestResume(t *testing.T) {
+	api := apitest.New(map[string]models.Rows{"select 1 as one": {{"one": "1"}}})
+	s, out := newTestSession(t, api, "h")
+	complete, _ := api.ScheduleQuery("h", "select 1 as one")
+	failed, _ := api.ScheduleQuery("h", "select * from missing")
+
+	if err := resume(s, ".resume "+complete); err != nil || !strings.Contains(out.String(), "| 1   |") {
+		t.Fatalf("resume() = %v, output %q", err, out)
+	}
+	if err := resume(s, ".resume "+failed); err == nil || !strings.Contains(err.Error(), "no such table") {
+		t.Fatalf("resume() of a failed query = %v, want the host's error", err)
+	}
+	if err := resume(s, ".resume unknown"); err == nil || err.Error() != "No query named unknown was found" {
+		t.Fatalf("resume() of an unknown query = %v", err)
+	}
+}
@@ -4,40 +4,63 @@ import (
 	"fmt"
 	"strings"
 
-	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/hosts"
-	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
+	"github.com/AbGuthrie/goquery/v2/utils"
 
 	prompt "github.com/c-bata/go-prompt"
 )
 
-func schedule(api models.GoQueryAPI, config *config.Config, cmdline string) error {
-	host, err := hosts.GetCurrentHost()
-	if err != nil {
-		return fmt.Errorf("No host is currently connected: %s", err)
-	}
-
+func schedule(s *session.Session, cmdline string) error {
 	args := strings.Split(cmdline, " ") // Separate command and arguments
 	if len(args) == 1 {
 		return fmt.Errorf("A query to run must be provided")
 	}
 	// TODO This needs to support Unicode/Runes
 	commandStripped := cmdline[strings.Index(cmdline, " ")+1:]
-	queryName, err := api.ScheduleQuery(host.UUID, commandStripped)
+
+	targets, commandStripped, fanOut, err := parseHostTargets(s, commandStripped)
+	if err != nil {
+		return err
+	}
+	if len(commandStripped) == 0 {
+		return fmt.Errorf("A query to run must be provided")
+	}
+	ctx, cancel := s.QueryContext()
+	defer cancel()
+
+	if fanOut {
+		hostResults := s.ScheduleQueryOnHosts(ctx, targets, commandStripped)
+		for _, result := range hostResults {
+			if result.Err == nil {
+				s.Jobs.Track(result.Host, result.QueryName, commandStripped)
+			}
+		}
+		s.PrintRows(utils.HostResultSummary(hostResults))
+		fmt.Fprintf(s.Out, "Resume or .wait on each host's results with its query name\n")
+		return nil
+	}
+
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return fmt.Errorf("No host is currently connected: %s", err)
+	}
+	queryName, err := s.ScheduleQuery(ctx, host.UUID, commandStripped)
 
 	if err != nil {
 		return err
 	}
+	s.Jobs.Track(host, queryName, commandStripped)
 
-	fmt.Printf("Scheduled query for host. Resume with name: %s\n", queryName)
+	fmt.Fprintf(s.Out, "Scheduled query for host. Resume with name: %s\n", queryName)
 
 	return nil
 }
 
 func scheduleHelp() string {
-	return "Schedule a query on a host but don't wait for results"
+	return "Schedule a query on a host but don't wait for results. " +
+		"Use --all, --hosts UUID1,UUID2 or --group NAME to schedule on many connected hosts"
 }
 
-func scheduleSuggest(cmdline string) []prompt.Suggest {
-	return querySuggest(cmdline)
+func scheduleSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	return querySuggest(s, cmdline)
 }
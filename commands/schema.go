@@ -0,0 +1,52 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+func printSchema(s *session.Session, cmdline string) error {
+	args := strings.Split(cmdline, " ") // Separate command and arguments
+	if len(args) != 2 || len(args[1]) == 0 {
+		return fmt.Errorf("A table name must be provided")
+	}
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return fmt.Errorf("No host is currently connected: %s", err)
+	}
+
+	columns, err := s.TableColumns(host.UUID, args[1])
+	if err != nil {
+		return err
+	}
+
+	columnRows := make([]map[string]string, 0)
+	for _, column := range columns {
+		columnRows = append(columnRows, map[string]string{
+			"name": column.Name,
+			"type": column.Type,
+		})
+	}
+	s.PrintRows(columnRows)
+	return nil
+}
+
+func printSchemaHelp() string {
+	return "Print the columns and types of a table on the current host"
+}
+
+func printSchemaSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	prompts := []prompt.Suggest{}
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return prompts
+	}
+	for _, table := range host.Tables {
+		prompts = append(prompts, prompt.Suggest{Text: table, Description: ""})
+	}
+	return prompts
+}
@@ -0,0 +1,103 @@
+package commands
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+// tableReferencePattern finds tables after from or join along with an optional alias
+var tableReferencePattern = regexp.MustCompile(`(?i)\b(?:from|join)\s+(\w+)(?:\s+(?:as\s+)?(\w+))?`)
+
+// aliasKeywords can follow a table name so they are never taken as its alias
+var aliasKeywords = map[string]bool{
+	"as": true, "cross": true, "group": true, "having": true, "inner": true, "join": true,
+	"left": true, "limit": true, "natural": true, "on": true, "order": true, "outer": true,
+	"union": true, "using": true, "where": true,
+}
+
+// columnKeywords are the words after which a column name is expected
+var columnKeywords = map[string]bool{
+	"and": true, "by": true, "having": true, "on": true, "or": true, "select": true, "where": true,
+}
+
+// sqlSuggest completes table names after from or join, and the columns of the tables a
+// query references after select, where, and, order by, a comma or an alias followed by a
+// dot. sql is the query typed so far, the last word of which is completed.
+func sqlSuggest(s *session.Session, host hosts.Host, sql string) []prompt.Suggest {
+	words := strings.Split(sql, " ")
+	current := words[len(words)-1]
+	previous := ""
+	if len(words) > 1 {
+		previous = strings.ToLower(words[len(words)-2])
+	}
+
+	if previous == "from" || previous == "join" {
+		prompts := []prompt.Suggest{}
+		for _, table := range host.Tables {
+			prompts = append(prompts, prompt.Suggest{Text: table, Description: ""})
+		}
+		return prompts
+	}
+
+	tables, aliases := referencedTables(host, sql)
+	if dot := strings.Index(current, "."); dot > 0 {
+		table, ok := aliases[current[:dot]]
+		if !ok {
+			return []prompt.Suggest{}
+		}
+		return columnSuggest(s, host, []string{table}, current, current[:dot+1])
+	}
+	if columnKeywords[previous] || strings.HasSuffix(previous, ",") {
+		return columnSuggest(s, host, tables, current, "")
+	}
+	return []prompt.Suggest{}
+}
+
+// referencedTables returns the tables on host that sql selects from in order, and a map
+// from every alias, and every table name itself, to its table
+func referencedTables(host hosts.Host, sql string) ([]string, map[string]string) {
+	known := make(map[string]bool, len(host.Tables))
+	for _, table := range host.Tables {
+		known[table] = true
+	}
+
+	tables := make([]string, 0)
+	aliases := make(map[string]string)
+	for _, match := range tableReferencePattern.FindAllStringSubmatch(sql, -1) {
+		table, alias := match[1], match[2]
+		// Skip partly typed or unknown names so no schema lookup is wasted on them
+		if !known[table] {
+			continue
+		}
+		if _, seen := aliases[table]; !seen {
+			tables = append(tables, table)
+		}
+		aliases[table] = table
+		if alias != "" && !aliasKeywords[strings.ToLower(alias)] {
+			aliases[alias] = table
+		}
+	}
+	return tables, aliases
+}
+
+// columnSuggest offers the columns of every table, each prefixed with prefix. current is
+// the word being completed, used to show that columns are still being fetched.
+func columnSuggest(s *session.Session, host hosts.Host, tables []string, current, prefix string) []prompt.Suggest {
+	prompts := []prompt.Suggest{}
+	for _, table := range tables {
+		columns, ok := s.CachedTableColumns(host.UUID, table)
+		if !ok {
+			prompts = append(prompts, prompt.Suggest{Text: current, Description: "Fetching columns of " + table + "..."})
+			continue
+		}
+		for _, column := range columns {
+			prompts = append(prompts, prompt.Suggest{Text: prefix + column.Name, Description: column.Type + " (" + table + ")"})
+		}
+	}
+	return prompts
+}
@@ -0,0 +1,109 @@
+package commands
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/internal/apitest"
+	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
+)
+
+// newSchemaSession returns a session connected to host "h" with the processes and users
+// tables, the columns of processes already known and those of users on the host
+func newSchemaSession(t *testing.T) (*session.Session, *apitest.API, *bytes.Buffer) {
+	api := apitest.New(map[string]models.Rows{
+		"pragma table_info(users)": {{"name": "uid", "type": "BIGINT"}, {"name": "username", "type": "TEXT"}},
+	})
+	s, out := newTestSession(t, api, "h")
+	s.Hosts.SetHostTables("h", []string{"processes", "users"})
+	s.Hosts.SetHostTableColumns("h", "processes", []hosts.Column{{Name: "pid", Type: "BIGINT"}, {Name: "name", Type: "TEXT"}})
+	return s, api, out
+}
+
+func suggestionTexts(s *session.Session, sql string) []string {
+	host, _ := s.Hosts.GetHost("h")
+	texts := make([]string, 0)
+	for _, suggestion := range sqlSuggest(s, host, sql) {
+		texts = append(texts, suggestion.Text)
+	}
+	return texts
+}
+
+func TestSQLSuggest(t *testing.T) {
+	s, api, _ := newSchemaSession(t)
+	tests := []struct {
+		sql  string
+		want []string
+	}{
+		{"select * from ", []string{"processes", "users"}},
+		{"select * from processes join ", []string{"processes", "users"}},
+		{"select ", []string{}},
+		{"select * from processes where ", []string{"pid", "name"}},
+		{"select * from processes p where p.", []string{"p.pid", "p.name"}},
+		{"select * from processes as p where p.", []string{"p.pid", "p.name"}},
+		{"select * from processes where pid = 1 and ", []string{"pid", "name"}},
+		{"select pid, ", []string{}},
+		{"select * from missing where ", []string{}},
+		{"select * from processes where x.", []string{}},
+	}
+	for _, test := range tests {
+		if got := suggestionTexts(s, test.sql); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("sqlSuggest(%q) = %q, want %q", test.sql, got, test.want)
+		}
+	}
+	if len(api.Scheduled()) != 0 {
+		t.Fatalf("known columns should not be looked up, scheduled %q", api.Scheduled())
+	}
+}
+
+func TestSQLSuggestFetchesColumnsInTheBackground(t *testing.T) {
+	s, api, _ := newSchemaSession(t)
+	host, _ := s.Hosts.GetHost("h")
+	suggestions := sqlSuggest(s, host, "select * from users where ")
+	if len(suggestions) != 1 || !strings.Contains(suggestions[0].Description, "Fetching columns of users") {
+		t.Fatalf("expected a fetching note, got %v", suggestions)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		if got := suggestionTexts(s, "select * from users where "); reflect.DeepEqual(got, []string{"uid", "username"}) {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("the columns of users never arrived")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if scheduled := api.Scheduled(); len(scheduled) != 1 {
+		t.Fatalf("expected one schema lookup, scheduled %q", scheduled)
+	}
+	if host, _ := s.Hosts.GetHost("h"); len(host.QueryHistory) != 0 {
+		t.Fatalf("schema lookups should not be recorded: %v", host.QueryHistory)
+	}
+}
+
+func TestPrintSchema(t *testing.T) {
+	s, api, out := newSchemaSession(t)
+	if err := printSchema(s, ".schema users"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "| username | TEXT   |") {
+		t.Fatalf("unexpected output:\n%s", out.String())
+	}
+	// The columns are kept on the host so the second lookup doesn't query it
+	printSchema(s, ".schema users")
+	if len(api.Scheduled()) != 1 {
+		t.Fatalf("expected one schema lookup, scheduled %q", api.Scheduled())
+	}
+	if err := printSchema(s, ".schema missing"); err == nil {
+		t.Fatal("expected an error for a table the host doesn't have")
+	}
+	if err := printSchema(s, ".schema users;drop"); err == nil || !strings.Contains(err.Error(), "Invalid table name") {
+		t.Fatalf("printSchema() of a bad name = %v", err)
+	}
+}
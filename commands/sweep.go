@@ -0,0 +1,194 @@
+package commands
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/session"
+	"github.com/AbGuthrie/goquery/v2/utils"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+var sweepHashesTemplate = "select path, md5, sha1, sha256 from hash where path like %s and (%s)"
+
+// iocChunkSize is the most hashes put in a single sweep query, larger IOC lists are
+// split so no query grows too long for the backend
+const iocChunkSize = 500
+
+// iocHashColumns maps the length of a hex encoded hash to the hash table column it matches
+var iocHashColumns = map[int]string{
+	32: "md5",
+	40: "sha1",
+	64: "sha256",
+}
+
+func sweep(s *session.Session, cmdline string) error {
+	args := strings.SplitN(cmdline, " ", 3)
+	if len(args) < 3 || args[1] != "hashes" {
+		return fmt.Errorf("Usage: .sweep hashes [--all | --hosts UUID1,UUID2 | --group NAME] IOC_FILE DIR")
+	}
+
+	targets, arguments, fanOut, err := parseHostTargets(s, args[2])
+	if err != nil {
+		return err
+	}
+	if !fanOut {
+		host, err := s.Hosts.GetCurrentHost()
+		if err != nil {
+			return fmt.Errorf("No host is currently connected: %s", err)
+		}
+		targets = []hosts.Host{host}
+	}
+
+	args = strings.Fields(arguments)
+	if len(args) != 2 {
+		return fmt.Errorf("An IOC file and a directory to sweep must be provided")
+	}
+	iocs, err := readHashIOCs(args[0])
+	if err != nil {
+		return err
+	}
+	if len(iocs) == 0 {
+		return fmt.Errorf("No hashes found in %s", args[0])
+	}
+
+	// Each host has its own current directory so relative paths only work on one
+	dir := args[1]
+	if !fanOut {
+		dir = resolveRemotePath(targets[0], dir)
+	} else if dir[0] != '/' {
+		return fmt.Errorf("The directory must be absolute when sweeping many hosts")
+	}
+	return sweepHashes(s, targets, iocs, dir)
+}
+
+// readHashIOCs reads one md5, sha1 or sha256 per line from a local file. Blank lines and
+// lines starting with # are skipped, anything else that isn't a hash is an error.
+func readHashIOCs(iocPath string) (map[string]string, error) {
+	iocFile, err := os.Open(iocPath)
+	if err != nil {
+		return nil, fmt.Errorf("Could not open IOC file: %s", err)
+	}
+	defer iocFile.Close()
+
+	iocs := make(map[string]string)
+	scanner := bufio.NewScanner(iocFile)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.ToLower(strings.TrimSpace(scanner.Text()))
+		if len(line) == 0 || line[0] == '#' {
+			continue
+		}
+		column, ok := iocHashColumns[len(line)]
+		if !ok || strings.Trim(line, "0123456789abcdef") != "" {
+			return nil, fmt.Errorf("Line %d of %s is not an md5, sha1 or sha256 hash", lineNumber, iocPath)
+		}
+		iocs[line] = column
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("Could not read IOC file: %s", err)
+	}
+	return iocs, nil
+}
+
+// sweepHashes hashes every file in dir on each target and prints the files matching one
+// of iocs, running one query per chunk of hashes on all targets at once
+func sweepHashes(s *session.Session, targets []hosts.Host, iocs map[string]string, dir string) error {
+	if dir[len(dir)-1] != '/' {
+		dir += "/"
+	}
+	constraints := hashIOCConstraints(iocs)
+	fmt.Fprintf(s.Out, "Sweeping %s for %d hash(es) on %d host(s) in %d chunk(s)...\n", dir, len(iocs), len(targets), len(constraints))
+
+	// Collect each host's chunks into a single result, keeping its first failure
+	hostResults := make([]utils.HostResult, len(targets))
+	for _, constraint := range constraints {
+		sweepQuery := fmt.Sprintf(sweepHashesTemplate, sqlString(dir+"%"), constraint)
+		for i, result := range s.ScheduleQueryOnHostsAndWait(targets, sweepQuery) {
+			combined := &hostResults[i]
+			combined.Host = result.Host
+			if combined.QueryName != "" {
+				combined.QueryName += ","
+			}
+			combined.QueryName += result.QueryName
+			combined.Rows = append(combined.Rows, result.Rows...)
+			if combined.Err == nil {
+				combined.Status = result.Status
+				combined.Err = result.Err
+			}
+		}
+	}
+
+	matches := utils.MergeHostResults(hostResults)
+	for _, row := range matches {
+		for _, column := range []string{"md5", "sha1", "sha256"} {
+			if _, ok := iocs[row[column]]; ok {
+				row["ioc"] = row[column]
+			}
+		}
+	}
+	if len(matches) > 0 {
+		s.PrintResults(matches)
+	} else {
+		fmt.Fprintf(s.Out, "No matching files found\n")
+	}
+	s.PrintRows(utils.HostResultSummary(hostResults))
+
+	for _, result := range hostResults {
+		if result.Err != nil {
+			return fmt.Errorf("Sweep failed on one or more hosts")
+		}
+	}
+	return nil
+}
+
+// hashIOCConstraints splits iocs into chunks of at most iocChunkSize and returns the
+// where clause matching a file against each chunk
+func hashIOCConstraints(iocs map[string]string) []string {
+	sortedIOCs := make([]string, 0, len(iocs))
+	for ioc := range iocs {
+		sortedIOCs = append(sortedIOCs, ioc)
+	}
+	sort.Strings(sortedIOCs)
+
+	chunks := make([]string, 0, (len(sortedIOCs)+iocChunkSize-1)/iocChunkSize)
+	for start := 0; start < len(sortedIOCs); start += iocChunkSize {
+		end := start + iocChunkSize
+		if end > len(sortedIOCs) {
+			end = len(sortedIOCs)
+		}
+		byColumn := make(map[string][]string)
+		for _, ioc := range sortedIOCs[start:end] {
+			byColumn[iocs[ioc]] = append(byColumn[iocs[ioc]], ioc)
+		}
+		constraints := make([]string, 0, len(byColumn))
+		for _, column := range []string{"md5", "sha1", "sha256"} {
+			if hashes, ok := byColumn[column]; ok {
+				constraints = append(constraints, fmt.Sprintf("%s in ('%s')", column, strings.Join(hashes, "', '")))
+			}
+		}
+		chunks = append(chunks, strings.Join(constraints, " or "))
+	}
+	return chunks
+}
+
+func sweepHelp() string {
+	return "Sweep a directory for files matching a local list of md5, sha1 or sha256 hashes. " +
+		"Use --all, --hosts UUID1,UUID2 or --group NAME to sweep many connected hosts"
+}
+
+func sweepSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	args := strings.Split(cmdline, " ")
+	if len(args) == 2 {
+		return prompt.FilterHasPrefix([]prompt.Suggest{
+			{Text: "hashes", Description: "Find files matching a list of hashes"},
+		}, args[1], false)
+	}
+	return hostTargetSuggest(s, strings.Join(args[1:], " "))
+}
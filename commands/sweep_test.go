@@ -0,0 +1,71 @@
+package commands
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/internal/apitest"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestSweepHashesChunksLargeIOCLists(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goquery-sweep")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	iocs := make([]string, 0)
+	for i := 0; i < 2*iocChunkSize+100; i++ {
+		iocs = append(iocs, fmt.Sprintf("%032x", i))
+	}
+	sha256 := strings.Repeat("a", 64)
+	iocPath := filepath.Join(dir, "iocs.txt")
+	ioutil.WriteFile(iocPath, []byte("# known bad\n"+strings.Join(iocs, "\n")+"\n"+sha256+"\n"), 0600)
+
+	api := apitest.New(map[string]models.Rows{})
+	s, out := newTestSession(t, api, "h")
+	sweep(s, ".sweep hashes "+iocPath+" /tmp")
+	queries := api.Scheduled()
+	if len(queries) != 3 {
+		t.Fatalf("expected 3 sweep queries, scheduled %d", len(queries))
+	}
+	for _, query := range queries {
+		if !strings.HasPrefix(query, "select path, md5, sha1, sha256 from hash where path like '/tmp/%' and (") {
+			t.Fatalf("unexpected sweep query: %.200s", query)
+		}
+	}
+	if !strings.Contains(queries[2], "sha256 in ('"+sha256+"')") {
+		t.Fatalf("the last sweep query is missing the sha256")
+	}
+	for _, ioc := range iocs {
+		found := 0
+		for _, query := range queries {
+			found += strings.Count(query, "'"+ioc+"'")
+		}
+		if found != 1 {
+			t.Fatalf("%s is in %d sweep queries", ioc, found)
+		}
+	}
+
+	// Answer the second chunk with a match and check it's merged with the others
+	api.SetResults(queries[0], models.Rows{})
+	api.SetResults(queries[1], models.Rows{{"path": "/tmp/x", "md5": iocs[iocChunkSize+3], "sha1": "", "sha256": ""}})
+	api.SetResults(queries[2], models.Rows{})
+	out.Reset()
+	if err := sweep(s, ".sweep hashes "+iocPath+" /tmp"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(out.String(), "/tmp/x") || !strings.Contains(out.String(), iocs[iocChunkSize+3]) {
+		t.Fatalf("match not reported:\n%s", out)
+	}
+
+	// A failed chunk fails the sweep on that host
+	api.RemoveResults(queries[2])
+	if err := sweep(s, ".sweep hashes "+iocPath+" /tmp"); err == nil {
+		t.Fatal("sweep with a failed chunk did not fail")
+	}
+}
@@ -0,0 +1,124 @@
+package commands
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/session"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+// parseHostTargets consumes any leading host targeting flags (--all, --hosts, --group)
+// from a command's argument string. It returns the hosts to fan out to, the rest of the
+// argument string and whether a targeting flag was present at all.
+func parseHostTargets(s *session.Session, arguments string) ([]hosts.Host, string, bool, error) {
+	connected := s.Hosts.GetCurrentHosts()
+	targets := make([]hosts.Host, 0)
+	fanOut := false
+
+	for {
+		parts := strings.SplitN(arguments, " ", 2)
+		flag := parts[0]
+		if flag != "--all" && flag != "--hosts" && flag != "--group" {
+			break
+		}
+		fanOut = true
+		if len(parts) == 1 {
+			arguments = ""
+		} else {
+			arguments = parts[1]
+		}
+
+		if flag == "--all" {
+			targets = append(targets, connected...)
+			continue
+		}
+
+		// Both --hosts and --group take a value
+		parts = strings.SplitN(arguments, " ", 2)
+		if len(parts[0]) == 0 {
+			return targets, arguments, fanOut, fmt.Errorf("%s requires a value", flag)
+		}
+		value := parts[0]
+		arguments = ""
+		if len(parts) == 2 {
+			arguments = parts[1]
+		}
+
+		if flag == "--hosts" {
+			for _, uuid := range strings.Split(value, ",") {
+				host, ok := findConnectedHost(connected, uuid)
+				if !ok {
+					return targets, arguments, fanOut, fmt.Errorf("No active host connection with uuid %s", uuid)
+				}
+				targets = append(targets, host)
+			}
+			continue
+		}
+
+		group, ok := s.Config.HostGroups[value]
+		if !ok {
+			return targets, arguments, fanOut, fmt.Errorf("No such host group: %s", value)
+		}
+		for _, uuid := range group {
+			host, ok := findConnectedHost(connected, uuid)
+			if !ok {
+				fmt.Fprintf(s.Out, "Skipping %s from group '%s', it is not connected\n", uuid, value)
+				continue
+			}
+			targets = append(targets, host)
+		}
+	}
+
+	if fanOut && len(targets) == 0 {
+		return targets, arguments, fanOut, fmt.Errorf("No connected hosts matched the provided targets")
+	}
+	return dedupeHosts(targets), arguments, fanOut, nil
+}
+
+func findConnectedHost(connected []hosts.Host, uuid string) (hosts.Host, bool) {
+	for _, host := range connected {
+		if host.UUID == uuid {
+			return host, true
+		}
+	}
+	return hosts.Host{}, false
+}
+
+func dedupeHosts(targets []hosts.Host) []hosts.Host {
+	seen := make(map[string]bool)
+	unique := make([]hosts.Host, 0)
+	for _, host := range targets {
+		if seen[host.UUID] {
+			continue
+		}
+		seen[host.UUID] = true
+		unique = append(unique, host)
+	}
+	return unique
+}
+
+// hostTargetSuggest offers the host targeting flags and their values
+func hostTargetSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	args := strings.Split(cmdline, " ")
+	if len(args) < 2 {
+		return []prompt.Suggest{}
+	}
+	if args[len(args)-2] == "--hosts" {
+		prompts := []prompt.Suggest{}
+		for _, host := range s.Hosts.GetCurrentHosts() {
+			prompts = append(prompts, prompt.Suggest{Text: host.UUID, Description: host.ComputerName})
+		}
+		return prompts
+	}
+	if len(args) == 2 {
+		return []prompt.Suggest{
+			{Text: "--all", Description: "Run on every connected host"},
+			{Text: "--hosts", Description: "Run on a comma separated list of connected host UUIDs"},
+			{Text: "--group", Description: "Run on the connected members of a configured host group"},
+		}
+	}
+	return []prompt.Suggest{}
+}
@@ -0,0 +1,45 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/AbGuthrie/goquery/v2/jobs"
+	"github.com/AbGuthrie/goquery/v2/session"
+	"github.com/AbGuthrie/goquery/v2/utils"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+func wait(s *session.Session, cmdline string) error {
+	args := strings.Split(cmdline, " ") // Separate command and arguments
+	if len(args) != 2 {
+		return fmt.Errorf("A job name to wait on must be provided")
+	}
+
+	ctx, cancel := utils.InterruptContext(context.Background())
+	defer cancel()
+	job, err := s.Jobs.Wait(ctx, args[1])
+	if err != nil {
+		return err
+	}
+
+	switch job.Status {
+	case jobs.StatusCancelled:
+		return fmt.Errorf("Job was cancelled")
+	case jobs.StatusFailed, jobs.StatusExpired:
+		return job.Err
+	}
+
+	s.PrintResults(job.Rows)
+	return nil
+}
+
+func waitHelp() string {
+	return "Block until a scheduled query finishes and print its results"
+}
+
+func waitSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	return jobSuggest(s, func(job jobs.Job) bool { return true })
+}
@@ -0,0 +1,117 @@
+package commands
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
+	"github.com/AbGuthrie/goquery/v2/utils"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+const defaultWatchInterval = 5 * time.Second
+
+func watch(s *session.Session, cmdline string) error {
+	host, err := s.Hosts.GetCurrentHost()
+	if err != nil {
+		return fmt.Errorf("No host is currently connected: %s", err)
+	}
+
+	interval := defaultWatchInterval
+	showDiff := false
+	var key []string
+	arguments := strings.TrimSpace(cmdline[len(strings.Fields(cmdline)[0]):])
+	for strings.HasPrefix(arguments, "-") {
+		parts := strings.SplitN(arguments, " ", 3)
+		switch parts[0] {
+		case "--diff":
+			showDiff = true
+			arguments = strings.TrimSpace(arguments[len(parts[0]):])
+			continue
+		case "-n", "--key":
+		default:
+			return fmt.Errorf("Unknown option: %s", parts[0])
+		}
+		if len(parts) < 2 {
+			return fmt.Errorf("%s requires a value", parts[0])
+		}
+		if parts[0] == "-n" {
+			seconds, err := strconv.Atoi(parts[1])
+			if err != nil || seconds <= 0 {
+				return fmt.Errorf("Invalid interval: %s", parts[1])
+			}
+			interval = time.Duration(seconds) * time.Second
+		} else {
+			key = strings.Split(parts[1], ",")
+			showDiff = true
+		}
+		arguments = ""
+		if len(parts) == 3 {
+			arguments = strings.TrimSpace(parts[2])
+		}
+	}
+	if len(arguments) == 0 {
+		return fmt.Errorf("Usage: .watch [-n SECONDS] [--diff] [--key COLUMN1,COLUMN2] QUERY")
+	}
+
+	// Ctrl C stops the whole watch, not just the query running when it is pressed
+	ctx, cancel := utils.InterruptContext(context.Background())
+	defer cancel()
+
+	var previous models.Rows
+	for iteration := 0; ; iteration++ {
+		results, err := s.ScheduleQueryAndWait(host.UUID, arguments)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		fmt.Fprintf(s.Out, "Every %s on %s at %s: %s\n", interval, host.UUID, time.Now().Format("15:04:05"), arguments)
+		if !showDiff || iteration == 0 {
+			s.PrintRows(results)
+		} else {
+			removed, added, changed := utils.DiffRows(previous, results, key)
+			changes := append(utils.TagRows(removed, "diff", "-"), utils.TagRows(added, "diff", "+")...)
+			changes = append(changes, utils.TagRows(utils.ChangedRows(changed), "diff", "~")...)
+			if len(changes) == 0 {
+				fmt.Fprintf(s.Out, "No changes\n")
+			} else {
+				s.PrintRows(changes)
+			}
+		}
+		s.LastResults = results
+		previous = results
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(interval):
+		}
+	}
+}
+
+func watchHelp() string {
+	return "Run a query on the current host every few seconds until ctrl C is pressed. " +
+		"--diff prints only the rows added (+) or removed (-) since the last run, matched on every column or the --key columns. " +
+		"With --key rows whose other columns changed are printed as well (~)"
+}
+
+func watchSuggest(s *session.Session, cmdline string) []prompt.Suggest {
+	args := strings.Split(cmdline, " ")
+	last := args[len(args)-1]
+	if len(args) >= 2 && strings.HasPrefix(last, "-") {
+		return prompt.FilterHasPrefix([]prompt.Suggest{
+			{Text: "-n", Description: fmt.Sprintf("Seconds between runs (default %d)", int(defaultWatchInterval.Seconds()))},
+			{Text: "--diff", Description: "Only print rows that changed since the last run"},
+			{Text: "--key", Description: "Comma separated columns that identify a row when diffing"},
+		}, last, false)
+	}
+	return querySuggest(s, cmdline)
+}
@@ -3,8 +3,10 @@
 package config
 
 import (
+	"encoding/json"
 	"fmt"
 	"strings"
+	"time"
 )
 
 // Alias is the struct used to allow abstracted commands
@@ -16,13 +18,27 @@ type Alias struct {
 
 // Config is the struct containing the application state
 type Config struct {
-	DebugEnabled bool             `json:"debugEnabled"`
-	APIDriver    string           `json:"apiDriver"`
-	Experimental bool             `json:"experimental"`
-	PrintMode    PrintModeEnum    `json:"printMode"`
-	Aliases      map[string]Alias `json:"aliases"`
+	DebugEnabled      bool                       `json:"debugEnabled"`
+	APIDriver         string                     `json:"apiDriver"`
+	Experimental      bool                       `json:"experimental"`
+	PrintMode         PrintModeEnum              `json:"printMode"`
+	Aliases           map[string]Alias           `json:"aliases"`
+	HostGroups        map[string][]string        `json:"hostGroups"`
+	QueryTimeout      int                        `json:"queryTimeout"`
+	DisablePager      bool                       `json:"disablePager"`
+	MaxFileSize       int64                      `json:"maxFileSize"`
+	HistoryResultSize int64                      `json:"historyResultSize"`
+	QueryHistoryDir   string                     `json:"queryHistoryDir"`
+	QueryStore        bool                       `json:"queryStore"`
+	Drivers           map[string]json.RawMessage `json:"drivers"`
 }
 
+// DefaultQueryTimeout is used when QueryTimeout is not configured
+const DefaultQueryTimeout = 5 * time.Minute
+
+// DefaultMaxFileSize is used when MaxFileSize is not configured
+const DefaultMaxFileSize = 1024 * 1024
+
 // PrintModeEnum is a type to ensure SetPrintMode recieves a valid enum
 type PrintModeEnum string
 
@@ -31,6 +47,9 @@ const (
 	PrintJSON   PrintModeEnum = "json"
 	PrintLine   PrintModeEnum = "line"
 	PrintPretty PrintModeEnum = "pretty"
+	PrintCSV    PrintModeEnum = "csv"
+	PrintTSV    PrintModeEnum = "tsv"
+	PrintNDJSON PrintModeEnum = "ndjson"
 )
 
 // Validate is responsible for filtering incorrect aliases configured, and printing the state of debug modes
@@ -61,6 +80,32 @@ func (config *Config) Validate() {
 	}
 }
 
+// QueryDeadline is how long to wait on a single query's results before giving up,
+// QueryTimeout is configured in seconds
+func (config *Config) QueryDeadline() time.Duration {
+	if config.QueryTimeout <= 0 {
+		return DefaultQueryTimeout
+	}
+	return time.Duration(config.QueryTimeout) * time.Second
+}
+
+// FileSizeLimit is the largest remote file in bytes that will be read for viewing
+func (config *Config) FileSizeLimit() int64 {
+	if config.MaxFileSize <= 0 {
+		return DefaultMaxFileSize
+	}
+	return config.MaxFileSize
+}
+
+// HistoryResultLimit is the most bytes of results stored for each query. Results are only
+// stored when HistoryResultSize is set, so this is zero by default.
+func (config *Config) HistoryResultLimit() int64 {
+	if config.HistoryResultSize < 0 {
+		return 0
+	}
+	return config.HistoryResultSize
+}
+
 // SetPrintMode assigns .PrintMode on the current config struct
 func (config *Config) SetPrintMode(printMode PrintModeEnum) {
 	config.PrintMode = printMode
@@ -0,0 +1,57 @@
+package config
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/user"
+	"path"
+)
+
+// SystemConfigPath is used when there is no config file in the user's home folder
+const SystemConfigPath = "/var/goquery/config.json"
+
+// FindUserConfig returns configPath if it is set, otherwise ~/.goquery/config.json, and
+// falls back to SystemConfigPath if there is no config file in the home folder. A
+// configPath that was given but doesn't exist is an error rather than a fallback.
+func FindUserConfig(configPath string) (string, error) {
+	if configPath != "" {
+		if _, err := os.Stat(configPath); err != nil {
+			return "", fmt.Errorf("Unable to find config file: %s", err)
+		}
+		return configPath, nil
+	}
+
+	// No config file override provided, check for default in ~/goquery/config.json
+	usr, err := user.Current()
+	if err != nil {
+		fmt.Printf("Failed to fetch user info for home directory: %s\n", err)
+		return SystemConfigPath, nil
+	}
+	configPath = path.Join(usr.HomeDir, ".goquery/config.json")
+
+	// There is no home folder config so default to system wide
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		configPath = SystemConfigPath
+	}
+	return configPath, nil
+}
+
+// LoadUserConfig reads the config file FindUserConfig picks for configOverride. Unlike a
+// zero Config, the query store is on unless the file sets queryStore to false.
+func LoadUserConfig(configOverride string) (Config, error) {
+	configPath, err := FindUserConfig(configOverride)
+	if err != nil {
+		return Config{}, err
+	}
+	configBytes, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return Config{}, fmt.Errorf("Unable to read config file: %s at path %s", err, configPath)
+	}
+	decoded := Config{QueryStore: true}
+	if err := json.Unmarshal(configBytes, &decoded); err != nil {
+		return Config{}, fmt.Errorf("Unable to parse config file: %s at path %s", err, configPath)
+	}
+	return decoded, nil
+}
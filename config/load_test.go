@@ -0,0 +1,59 @@
+package config
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadUserConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "goquery-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	configPath := filepath.Join(dir, "config.json")
+	ioutil.WriteFile(configPath, []byte(`{"apiDriver": "local", "drivers": {"local": {"osqueryiPath": "/usr/bin/osqueryi"}}}`), 0600)
+	loaded, err := LoadUserConfig(configPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if loaded.APIDriver != "local" || string(loaded.Drivers["local"]) != `{"osqueryiPath": "/usr/bin/osqueryi"}` {
+		t.Fatalf("LoadUserConfig() = %+v", loaded)
+	}
+	if !loaded.QueryStore {
+		t.Fatal("LoadUserConfig() should turn the query store on by default")
+	}
+
+	ioutil.WriteFile(configPath, []byte(`{"queryStore": false}`), 0600)
+	if loaded, err := LoadUserConfig(configPath); err != nil || loaded.QueryStore {
+		t.Fatalf("LoadUserConfig() with queryStore false = %+v, %v", loaded, err)
+	}
+
+	ioutil.WriteFile(configPath, []byte(`{"apiDriver": `), 0600)
+	if _, err := LoadUserConfig(configPath); err == nil || !strings.Contains(err.Error(), "Unable to parse config file") {
+		t.Fatalf("LoadUserConfig() of bad JSON = %v", err)
+	}
+}
+
+func TestFindUserConfigRequiresAGivenPathToExist(t *testing.T) {
+	if found, err := FindUserConfig("/nonexistent/goquery.json"); err == nil {
+		t.Fatalf("FindUserConfig() of a missing file = %s, want an error", found)
+	}
+	if _, err := LoadUserConfig("/nonexistent/goquery.json"); err == nil || !strings.Contains(err.Error(), "Unable to find config file") {
+		t.Fatalf("LoadUserConfig() of a missing file = %v", err)
+	}
+
+	file, err := ioutil.TempFile("", "goquery-config")
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+	defer os.Remove(file.Name())
+	if found, err := FindUserConfig(file.Name()); err != nil || found != file.Name() {
+		t.Fatalf("FindUserConfig() of an existing file = %s, %v", found, err)
+	}
+}
@@ -1,13 +1,10 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"net/url"
 	"os"
-	"os/user"
-	"path"
 
 	"github.com/AbGuthrie/goquery/v2"
 	"github.com/AbGuthrie/goquery/v2/api/mock"
@@ -16,57 +13,13 @@ import (
 	"github.com/AbGuthrie/goquery/v2/models"
 )
 
-func parseConfigOverride(args []string) (string, error) {
-	if len(args) == 1 {
-		return "", fmt.Errorf("No override provided")
-	}
-
-	if len(args) < 3 {
-		panic("Invalid arguments provided, expecting --config 'path'")
-	}
-
-	if args[1] != "--config" {
-		panic("Invalid arguments provided, expecting --config 'path'")
-	}
-
-	return args[2], nil
-}
-
-func findUserConfig() string {
-	configPath, err := parseConfigOverride(os.Args)
-
-	// No config file override provided, check for default in ~/goquery/config.json
-	if err != nil {
-		usr, err := user.Current()
-		if err != nil {
-			fmt.Printf("Failed to fetch user info for home directory: %s\n", err)
-		} else {
-			configPath = path.Join(usr.HomeDir, ".goquery/config.json")
-		}
-	}
-
-	// There is no home folder config so default to system wide
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		configPath = "/var/goquery/config.json"
-	}
-	return configPath
-}
-
-func loadUserConfig() (config.Config, error) {
-	configPath := findUserConfig()
-	configBytes, err := ioutil.ReadFile(configPath)
-	if err != nil {
-		fmt.Printf("Unable to read config file: %s at path %s\n", err, configPath)
-	}
-	decoded := &config.Config{}
-	if err := json.Unmarshal(configBytes, &decoded); err != nil {
-		fmt.Printf("Unable to parse config file: %s at path %s\n", err, configPath)
-	}
-
-	return *decoded, nil
-}
-
 func main() {
+	configOverride := flag.String("config", "", "Path to a goquery config file")
+	commandString := flag.String("c", "", "Run ';' separated commands then exit")
+	scriptPath := flag.String("f", "", "Run the commands in a script file then exit")
+	continueOnError := flag.Bool("continue-on-error", false, "Keep running a script after a command fails")
+	flag.Parse()
+
 	// 1. Provide something that implements the required models/GoQueryAPI interface,
 	//	  or use a supported built in (see `api/mock` for example implementation)
 	// api := myCustomAPI{}
@@ -80,10 +33,13 @@ func main() {
 
 	// 2. Create goquery configuration options (aliases, print mode, debug etc.)
 	// You can load from a file or use a hardcoded config (we use a hardcoded config)
-	// on error loading from the user's home folder
-	cfg, err := loadUserConfig()
+	// on error loading from the user's home folder, but not from a -config file
+	cfg, err := config.LoadUserConfig(*configOverride)
 	if err != nil {
 		fmt.Printf("Couldn't load user config because of error: %s\n", err)
+		if *configOverride != "" {
+			os.Exit(1)
+		}
 		fmt.Println("Using defaults")
 
 		cfg = config.Config{
@@ -97,8 +53,21 @@ func main() {
 			},
 		}
 	}
-	// 3. Call goquery
-	goquery.Run(api, cfg)
+	// 3. Call goquery, either non interactively with a script or as a REPL
+	script, err := goquery.OpenScript(*commandString, *scriptPath)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	if script == nil {
+		goquery.Run(api, cfg)
+		return
+	}
+	defer script.Close()
+	if err := goquery.RunScript(api, cfg, script, os.Stdout, *continueOnError); err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
 }
 
 type myCustomAPI struct {
@@ -114,6 +83,6 @@ func (apiConfig myCustomAPI) ScheduleQuery(uuid string, query string) (string, e
 	return "", fmt.Errorf("Not implemented")
 }
 
-func (apiConfig myCustomAPI) FetchResults(queryToken string) (models.Rows, string, error) {
-	return models.Rows{}, "", fmt.Errorf("Not implemented")
+func (apiConfig myCustomAPI) FetchResults(queryToken string) (models.Rows, models.QueryStatus, error) {
+	return models.Rows{}, models.QueryStatus{}, fmt.Errorf("Not implemented")
 }
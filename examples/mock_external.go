@@ -15,6 +15,7 @@ import (
 	"github.com/AbGuthrie/goquery/v2/config"
 	"github.com/AbGuthrie/goquery/v2/hosts"
 	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/session"
 
 	prompt "github.com/c-bata/go-prompt"
 )
@@ -69,8 +70,8 @@ func loadUserConfig() (config.Config, error) {
 	return *decoded, nil
 }
 
-func externalExample(api models.GoQueryAPI, config *config.Config, cmdline string) error {
-	fmt.Println("Greetings from an external command!")
+func externalExample(s *session.Session, cmdline string) error {
+	fmt.Fprintln(s.Out, "Greetings from an external command!")
 	return nil
 }
 
@@ -78,7 +79,7 @@ func externalExampleHelp() string {
 	return "Example external command from outside goquery"
 }
 
-func externalExampleSuggest(cmdline string) []prompt.Suggest {
+func externalExampleSuggest(s *session.Session, cmdline string) []prompt.Suggest {
 	return []prompt.Suggest{}
 }
 
@@ -114,9 +115,9 @@ func main() {
 		}
 	}
 	commandMap := map[string]commands.GoQueryCommand{
-		".external": commands.GoQueryCommand{externalExample, externalExampleHelp, externalExampleSuggest},
+		".external": commands.GoQueryCommand{Execute: externalExample, Help: externalExampleHelp, Suggestions: externalExampleSuggest},
 		// Possible command that could be used to pull a file from a machine
-		//".get": commands.GoQueryCommand{get, getHelp, getSuggest},
+		//".get": commands.GoQueryCommand{Execute: get, Help: getHelp, Suggestions: getSuggest},
 	}
 	// 3. Call goquery
 	goquery.RunWithExternalCommands(api, cfg, commandMap)
@@ -135,6 +136,6 @@ func (apiConfig myCustomAPI) ScheduleQuery(uuid string, query string) (string, e
 	return "", fmt.Errorf("Not implemented")
 }
 
-func (apiConfig myCustomAPI) FetchResults(queryToken string) (models.Rows, string, error) {
-	return models.Rows{}, "", fmt.Errorf("Not implemented")
+func (apiConfig myCustomAPI) FetchResults(queryToken string) (models.Rows, models.QueryStatus, error) {
+	return models.Rows{}, models.QueryStatus{}, fmt.Errorf("Not implemented")
 }
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/AbGuthrie/goquery/v2"
+	"github.com/AbGuthrie/goquery/v2/api"
+	"github.com/AbGuthrie/goquery/v2/config"
+
+	// Registers the tlsserver driver with the api package
+	_ "github.com/AbGuthrie/goquery/v2/api/tlsserver"
+)
+
+func main() {
+	configOverride := flag.String("config", "", "Path to a goquery config file")
+	commandString := flag.String("c", "", "Run ';' separated commands then exit")
+	scriptPath := flag.String("f", "", "Run the commands in a script file then exit")
+	continueOnError := flag.Bool("continue-on-error", false, "Keep running a script after a command fails")
+	flag.Parse()
+
+	// The tlsserver block under drivers holds the listen address, certificate and
+	// enroll secret
+	cfg, err := config.LoadUserConfig(*configOverride)
+	if err != nil {
+		fmt.Printf("Couldn't load user config because of error: %s\n", err)
+		os.Exit(1)
+	}
+	goqueryAPI, err := api.Create("tlsserver", cfg.Drivers["tlsserver"], cfg.DebugEnabled)
+	if err != nil {
+		fmt.Printf("Encountered an error starting API: %s\n", err)
+		os.Exit(1)
+	}
+
+	script, err := goquery.OpenScript(*commandString, *scriptPath)
+	if err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+	if script == nil {
+		goquery.Run(goqueryAPI, cfg)
+		return
+	}
+	defer script.Close()
+	if err := goquery.RunScript(goqueryAPI, cfg, script, os.Stdout, *continueOnError); err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
+}
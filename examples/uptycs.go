@@ -1,73 +1,49 @@
 package main
 
 import (
-	"encoding/json"
+	"flag"
 	"fmt"
-	"io/ioutil"
 	"os"
-	"os/user"
-	"path"
 
 	"github.com/AbGuthrie/goquery/v2"
-	"github.com/AbGuthrie/goquery/v2/api/uptycs"
+	"github.com/AbGuthrie/goquery/v2/api"
+	"github.com/AbGuthrie/goquery/v2/config"
+
+	// Registers the uptycs driver with the api package
+	_ "github.com/AbGuthrie/goquery/v2/api/uptycs"
 )
 
-func parseConfigOverride(args []string) (string, error) {
-	if len(args) == 1 {
-		return "", fmt.Errorf("No override provided")
-	}
-	if len(args) < 3 {
-		panic("Invalid arguments provided, expecting --config 'path'")
-	}
-	if args[1] != "--config" {
-		panic("Invalid arguments provided, expecting --config 'path'")
-	}
-	return args[2], nil
-}
+func main() {
+	configOverride := flag.String("config", "", "Path to a goquery config file")
+	commandString := flag.String("c", "", "Run ';' separated commands then exit")
+	scriptPath := flag.String("f", "", "Run the commands in a script file then exit")
+	continueOnError := flag.Bool("continue-on-error", false, "Keep running a script after a command fails")
+	flag.Parse()
 
-func findUserConfig() string {
-	configPath, err := parseConfigOverride(os.Args)
+	// The uptycs block under drivers holds the Uptycs API credentials
+	cfg, err := config.LoadUserConfig(*configOverride)
 	if err != nil {
-		usr, err := user.Current()
-		if err != nil {
-			fmt.Printf("Failed to fetch user info for home directory: %s\n", err)
-		} else {
-			configPath = path.Join(usr.HomeDir, ".goquery/config.json")
-		}
+		fmt.Printf("Couldn't load user config because of error: %s\n", err)
+		os.Exit(1)
 	}
-	if _, err := os.Stat(configPath); os.IsNotExist(err) {
-		configPath = "/var/goquery/config.json"
-	}
-	return configPath
-}
-
-func loadUserConfig() (uptycs.GoqueryConfig, error) {
-	configPath := findUserConfig()
-	configBytes, err := ioutil.ReadFile(configPath)
+	goqueryAPI, err := api.Create("uptycs", cfg.Drivers["uptycs"], cfg.DebugEnabled)
 	if err != nil {
-		fmt.Printf("Unable to read config file: %s at path %s\n", err, configPath)
-	}
-	decoded := &uptycs.GoqueryConfig{}
-	if err := json.Unmarshal(configBytes, &decoded); err != nil {
-		fmt.Printf("Unable to parse config file: %s at path %s\n", err, configPath)
+		fmt.Printf("Encountered an error starting API: %s\n", err)
+		os.Exit(1)
 	}
-	return *decoded, nil
-}
 
-func main() {
-	cfg, err := loadUserConfig()
+	script, err := goquery.OpenScript(*commandString, *scriptPath)
 	if err != nil {
-		panic(
-			fmt.Errorf(
-				"Couldn't load user config because of error: %s\n",
-				err,
-			),
-		)
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
 	}
-	api, err := uptycs.CreateUptycsAPI(cfg.UptCfgPath, cfg.GoqueryConfig.DebugEnabled)
-	if err != nil {
-		fmt.Printf("Encountered an error starting API: %s\n", err)
+	if script == nil {
+		goquery.Run(goqueryAPI, cfg)
 		return
 	}
-	goquery.Run(api, cfg.GoqueryConfig)
+	defer script.Close()
+	if err := goquery.RunScript(goqueryAPI, cfg, script, os.Stdout, *continueOnError); err != nil {
+		fmt.Printf("%s\n", err)
+		os.Exit(1)
+	}
 }
@@ -2,149 +2,92 @@ package goquery
 
 import (
 	"fmt"
-	"sort"
+	"io"
+	"io/ioutil"
+	"os"
 	"strings"
 
 	"github.com/AbGuthrie/goquery/v2/commands"
 	"github.com/AbGuthrie/goquery/v2/config"
-	"github.com/AbGuthrie/goquery/v2/hosts"
 	"github.com/AbGuthrie/goquery/v2/models"
-	"github.com/AbGuthrie/goquery/v2/utils"
-
-	prompt "github.com/c-bata/go-prompt"
+	"github.com/AbGuthrie/goquery/v2/session"
 )
 
-var apiInstance models.GoQueryAPI
-var options config.Config
+// NewSession creates an independent goquery session with all built in commands that
+// writes its output to out. Many sessions can be used side by side in one process.
+func NewSession(api models.GoQueryAPI, _config config.Config, out io.Writer) *session.Session {
+	return session.New(api, _config, commands.DefaultCommands(), out)
+}
 
-// Run is the entry point for a file impporting the goquery library to start the prompt REPL
+// RunWithExternalCommands is the entry point for a file impporting the goquery library to start
+// the prompt REPL with additional commands
 func RunWithExternalCommands(api models.GoQueryAPI, _config config.Config, _externalCommandMap map[string]commands.GoQueryCommand) {
+	s := NewSession(api, _config, os.Stdout)
 	for k, v := range _externalCommandMap {
-		commands.CommandMap[k] = v
+		s.Commands[k] = v
 	}
-	Run(api, _config)
+	s.Run()
 }
 
 // Run is the entry point for a file impporting the goquery library to start the prompt REPL
 func Run(api models.GoQueryAPI, _config config.Config) {
-	// Print errors/warnings with provided aliases, and print state of debug flags
-	_config.Validate()
-
-	// Set globals for executor function closure
-	options = _config
-	apiInstance = api
-
-	history, err := utils.LoadHistoryFile()
-	if err != nil {
-		fmt.Printf("Unable to load history file %s\n", err)
-	}
-
-	p := prompt.New(
-		executor,
-		completer,
-		prompt.OptionPrefix("goquery> "),
-		prompt.OptionLivePrefix(refreshLivePrefix),
-		prompt.OptionTitle("goquery"),
-		prompt.OptionHistory(history),
-	)
-	p.Run()
+	NewSession(api, _config, os.Stdout).Run()
 }
 
-func refreshLivePrefix() (string, bool) {
-	// Prototype for showing current connected host state in
-	// input line prefix
-	subPrefix := ""
-	currentHost, err := hosts.GetCurrentHost()
-	if err == nil {
-		subPrefix = " | " + currentHost.ComputerName + ":" + currentHost.CurrentDirectory
-	}
-	return fmt.Sprintf("goquery%s> ", subPrefix), true
+// RunScript is the non interactive entry point. Every line read from script is run
+// through the same commands and aliases as the REPL, with all output written to output.
+// Blank lines and lines starting with # are skipped. Execution stops at the first failing
+// command unless continueOnError is set, and an error is returned if any command failed.
+func RunScript(api models.GoQueryAPI, _config config.Config, script io.Reader, output io.Writer, continueOnError bool) error {
+	return NewSession(api, _config, output).RunScript(script, continueOnError)
 }
 
-func executor(input string) {
-	writeHistory := true
-	defer func() {
-		if !writeHistory {
-			return
-		}
-		// Write history entry
-		if err := utils.UpdateHistoryFile(input); err != nil {
-			fmt.Printf("Failed to write history file: %s\n", err)
-		}
-	}()
-
-	// Separate command and arguments
-	input = strings.TrimSpace(input)
-	args := strings.Split(input, " ")
-	if len(args) == 0 {
-		return
+// OpenScript returns the script for RunScript given to a goquery binary, either the ';'
+// separated commands of -c or the file at the -f scriptPath, or nil if neither is set.
+// The caller must close it.
+func OpenScript(commands string, scriptPath string) (io.ReadCloser, error) {
+	if commands != "" {
+		return ioutil.NopCloser(strings.NewReader(strings.Join(SplitCommands(commands), "\n"))), nil
 	}
-
-	// Lookup and run command in command map
-	if command, ok := commands.CommandMap[args[0]]; ok {
-		err := command.Execute(apiInstance, &options, input)
-		if err != nil {
-			fmt.Printf("%s: %s\n", args[0], err.Error())
-		}
-		return
-	}
-
-	// Command not found, was this command aliased?
-	alias, found := options.Aliases[args[0]]
-	if !found {
-		fmt.Printf("No such command: %s\n", args[0])
-		return
+	if scriptPath == "" {
+		return nil, nil
 	}
-	realizedCommand, err := utils.InterpolateArguments(input, alias.Command)
+	scriptFile, err := os.Open(scriptPath)
 	if err != nil {
-		fmt.Printf("Alias error: %s\n", err)
-		return
+		return nil, fmt.Errorf("Unable to open script file: %s", err)
 	}
-
-	// Run the parsed and interpolated alias through executor again
-	writeHistory = false
-	executor(realizedCommand)
+	return scriptFile, nil
 }
 
-func completer(in prompt.Document) []prompt.Suggest {
-	command := strings.Split(in.CurrentLine(), " ")[0]
-	// Nothing has been typed at the prompt
-	if command == "" {
-		return []prompt.Suggest{}
-	}
-
-	// Suggest any top level command
-	if _, ok := commands.CommandMap[command]; !ok {
-		prompts := []prompt.Suggest{}
-		// We also need to sort the final array because go traverses maps non
-		// deterministically
-		suggestions := make([]string, 0)
-
-		// Add all command suggestions
-		for name := range commands.CommandMap {
-			suggestions = append(suggestions, name)
-		}
-		// Add all alias suggestions
-		for name := range options.Aliases {
-			suggestions = append(suggestions, name)
+// SplitCommands splits a ';' separated list of commands, such as the one given to -c, into
+// one command per entry. Semicolons inside single or double quotes don't separate
+// commands so SQL string literals are kept whole, and empty commands are dropped.
+func SplitCommands(commands string) []string {
+	split := make([]string, 0)
+	var current strings.Builder
+	var quote rune
+	flush := func() {
+		if command := strings.TrimSpace(current.String()); command != "" {
+			split = append(split, command)
 		}
-
-		sort.Strings(suggestions)
-		for _, suggestion := range suggestions {
-			if alias, ok := options.Aliases[suggestion]; ok {
-				description := alias.Description
-				if len(description) == 0 {
-					description = alias.Command
-				}
-				prompts = append(prompts, prompt.Suggest{Text: suggestion, Description: description})
-			} else if command, ok := commands.CommandMap[suggestion]; ok {
-				prompts = append(prompts, prompt.Suggest{Text: suggestion, Description: command.Help()})
+		current.Reset()
+	}
+	for _, char := range commands {
+		switch {
+		case quote != 0:
+			// A doubled quote escapes itself in SQL, which this handles by closing and
+			// reopening the quote
+			if char == quote {
+				quote = 0
 			}
+		case char == '\'' || char == '"':
+			quote = char
+		case char == ';':
+			flush()
+			continue
 		}
-		return prompt.FilterHasPrefix(prompts, command, true)
+		current.WriteRune(char)
 	}
-
-	// Call into the command to ask for further suggestions
-	commandStruct := commands.CommandMap[command]
-	return prompt.FilterHasPrefix(commandStruct.Suggestions(in.CurrentLine()), in.GetWordBeforeCursor(), true)
+	flush()
+	return split
 }
@@ -0,0 +1,78 @@
+package goquery
+
+import (
+	"bytes"
+	"io/ioutil"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/config"
+	"github.com/AbGuthrie/goquery/v2/internal/apitest"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestSplitCommands(t *testing.T) {
+	tests := []struct {
+		commands string
+		want     []string
+	}{
+		{".connect a; .query select 1", []string{".connect a", ".query select 1"}},
+		{".query select 1;", []string{".query select 1"}},
+		{".query select * from file where path like '%;%'; .exit", []string{".query select * from file where path like '%;%'", ".exit"}},
+		{`.query select "a;b" as x;;`, []string{`.query select "a;b" as x`}},
+		{".query select 'it''s;here'", []string{".query select 'it''s;here'"}},
+		{"", []string{}},
+	}
+	for _, test := range tests {
+		if got := SplitCommands(test.commands); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("SplitCommands(%q) = %q, want %q", test.commands, got, test.want)
+		}
+	}
+}
+
+func TestOpenScript(t *testing.T) {
+	script, err := OpenScript(".connect a; .query select 1", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	contents, _ := ioutil.ReadAll(script)
+	script.Close()
+	if string(contents) != ".connect a\n.query select 1" {
+		t.Fatalf("OpenScript() of -c commands = %q", contents)
+	}
+	if script, err := OpenScript("", ""); script != nil || err != nil {
+		t.Fatalf("OpenScript() without a script = %v, %v", script, err)
+	}
+	if _, err := OpenScript("", "/nonexistent/script.gq"); err == nil || !strings.Contains(err.Error(), "Unable to open script file") {
+		t.Fatalf("OpenScript() of a missing file = %v", err)
+	}
+}
+
+func TestRunScriptExit(t *testing.T) {
+	tests := []struct {
+		script          string
+		continueOnError bool
+		wantErr         bool
+		wantChecked     int
+	}{
+		{".connect a\n.exit\n.connect b", false, false, 1},
+		// An earlier failure still fails the script when it ends with .exit
+		{".nope\n.exit\n.connect b", true, true, 0},
+		{".nope\n.connect a", false, true, 0},
+		{".nope\n.connect a", true, true, 1},
+	}
+	for _, test := range tests {
+		// .connect reads the host's table list
+		api := apitest.New(map[string]models.Rows{
+			"select name from osquery_registry where registry = 'table' and active = 1": {},
+		})
+		err := RunScript(api, config.Config{}, strings.NewReader(test.script), &bytes.Buffer{}, test.continueOnError)
+		if (err != nil) != test.wantErr {
+			t.Errorf("RunScript(%q) error = %v, want error %v", test.script, err, test.wantErr)
+		}
+		if api.Checked() != test.wantChecked {
+			t.Errorf("RunScript(%q) connected %d hosts, want %d", test.script, api.Checked(), test.wantChecked)
+		}
+	}
+}
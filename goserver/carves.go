@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// carveSession is a carve being uploaded by osquery through the carver endpoints
+type carveSession struct {
+	RequestID  string
+	CarveID    string
+	BlockCount int
+	BlockSize  int
+	CarveSize  int64
+	Blocks     map[int][]byte
+}
+
+// Carve blocks are uploaded concurrently with goquery polling so guard both maps
+var carveMutex sync.Mutex
+
+// Maps Session ID -> carve session
+var carveSessions map[string]*carveSession
+
+// Maps Request ID, the name of the distributed query that started a carve -> Session ID
+var carveRequests map[string]string
+
+// Begin osquery carver endpoints
+func startUploads(w http.ResponseWriter, r *http.Request) {
+	type startUploadsRequest struct {
+		BlockCount int    `json:"block_count"`
+		BlockSize  int    `json:"block_size"`
+		CarveSize  int64  `json:"carve_size"`
+		CarveID    string `json:"carve_id"`
+		RequestID  string `json:"request_id"`
+		NodeKey    string `json:"node_key"`
+	}
+
+	jsonBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		fmt.Printf("Could not read body: %s\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	parsedRequest := startUploadsRequest{}
+	if err := json.Unmarshal(jsonBytes, &parsedRequest); err != nil {
+		fmt.Printf("Could not parse body: %s\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	if !isNodeKeyEnrolled(apiRequest{NodeKey: parsedRequest.NodeKey}) {
+		fmt.Fprintf(w, "{\"node_invalid\" : true}")
+		fmt.Printf("The host starting a carve is not enrolled\n")
+		return
+	}
+
+	sessionID := randomString(32)
+	carveMutex.Lock()
+	carveSessions[sessionID] = &carveSession{
+		RequestID:  parsedRequest.RequestID,
+		CarveID:    parsedRequest.CarveID,
+		BlockCount: parsedRequest.BlockCount,
+		BlockSize:  parsedRequest.BlockSize,
+		CarveSize:  parsedRequest.CarveSize,
+		Blocks:     make(map[int][]byte),
+	}
+	carveRequests[parsedRequest.RequestID] = sessionID
+	carveMutex.Unlock()
+
+	fmt.Printf("Started carve %s with %d block(s) for %s\n", parsedRequest.CarveID, parsedRequest.BlockCount, parsedRequest.RequestID)
+	fmt.Fprintf(w, "{\"session_id\" : \"%s\"}", sessionID)
+}
+
+func uploadBlocks(w http.ResponseWriter, r *http.Request) {
+	type uploadBlocksRequest struct {
+		BlockID   int    `json:"block_id"`
+		SessionID string `json:"session_id"`
+		RequestID string `json:"request_id"`
+		Data      string `json:"data"`
+	}
+
+	jsonBytes, err := ioutil.ReadAll(r.Body)
+	if err != nil {
+		fmt.Printf("Could not read body: %s\n", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	parsedRequest := uploadBlocksRequest{}
+	if err := json.Unmarshal(jsonBytes, &parsedRequest); err != nil {
+		fmt.Printf("Could not parse body: %s\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	block, err := base64.StdEncoding.DecodeString(parsedRequest.Data)
+	if err != nil {
+		fmt.Printf("Could not decode carve block: %s\n", err)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	carveMutex.Lock()
+	defer carveMutex.Unlock()
+	session, ok := carveSessions[parsedRequest.SessionID]
+	if !ok {
+		fmt.Printf("Received a block for unknown carve session: %s\n", parsedRequest.SessionID)
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	if parsedRequest.BlockID < 0 || parsedRequest.BlockID >= session.BlockCount {
+		fmt.Printf("Received out of range block %d for carve %s\n", parsedRequest.BlockID, session.CarveID)
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	session.Blocks[parsedRequest.BlockID] = block
+	fmt.Printf("Received block %d/%d for carve %s\n", len(session.Blocks), session.BlockCount, session.CarveID)
+	fmt.Fprintf(w, "{}")
+}
+
+// End osquery carver endpoints
+
+// Begin goquery carve APIs
+func startCarve(w http.ResponseWriter, r *http.Request) {
+	uuid := r.FormValue("uuid")
+	path := r.FormValue("path")
+	fmt.Printf("StartCarve call for: %s with path: %s\n", uuid, path)
+
+	carveQuery := fmt.Sprintf("select * from carves where path like '%s' and carve = 1", strings.ReplaceAll(path, "'", "''"))
+	queryName, err := queueQuery(uuid, carveQuery)
+	if err != nil {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	fmt.Fprintf(w, "{\"carveId\" : \"%s\"}", queryName)
+}
+
+func carveStatus(w http.ResponseWriter, r *http.Request) {
+	type carveStatusResponse struct {
+		Started        bool   `json:"started"`
+		BlockCount     int    `json:"blockCount"`
+		BlocksReceived int    `json:"blocksReceived"`
+		CarveSize      int64  `json:"carveSize"`
+		Error          string `json:"error"`
+	}
+
+	carveID := r.FormValue("carveId")
+	query, ok := lookupQuery(carveID)
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+
+	response := carveStatusResponse{}
+	carveMutex.Lock()
+	if sessionID, ok := carveRequests[carveID]; ok {
+		session := carveSessions[sessionID]
+		response.Started = true
+		response.BlockCount = session.BlockCount
+		response.BlocksReceived = len(session.Blocks)
+		response.CarveSize = session.CarveSize
+	}
+	carveMutex.Unlock()
+
+	// The carve query finishing without starting an upload means nothing was carved
+	if !response.Started && query.Complete {
+		if query.Status == "Failed" {
+			response.Error = query.Message
+		} else if rows := []json.RawMessage{}; json.Unmarshal(query.Result, &rows) == nil && len(rows) == 0 {
+			response.Error = "No files matched the carve path"
+		}
+	}
+
+	renderedResponse, err := json.Marshal(response)
+	if err != nil {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+	w.Write(renderedResponse)
+}
+
+func carveBlock(w http.ResponseWriter, r *http.Request) {
+	carveID := r.FormValue("carveId")
+	blockID, err := strconv.Atoi(r.FormValue("blockId"))
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+
+	carveMutex.Lock()
+	defer carveMutex.Unlock()
+	session, ok := carveSessions[carveRequests[carveID]]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	block, ok := session.Blocks[blockID]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		return
+	}
+	w.Write(block)
+}
+
+// End goquery carve APIs
+
+// lookupQuery finds a scheduled query by name on any host
+func lookupQuery(queryName string) (Query, bool) {
+	queryMutex.Lock()
+	defer queryMutex.Unlock()
+	for _, queries := range queryMap {
+		if query, ok := queries[queryName]; ok {
+			return query, true
+		}
+	}
+	return Query{}, false
+}
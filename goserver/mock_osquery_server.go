@@ -14,7 +14,9 @@ import (
 	"math/rand"
 	"net/http"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/crewjam/saml/samlsp"
@@ -26,6 +28,7 @@ type Query struct {
 	Complete bool
 	Result   json.RawMessage `json:"results"`
 	Status   string          `json:"status"`
+	Message  string          `json:"message"`
 }
 
 type Host struct {
@@ -44,6 +47,9 @@ var enrolledHosts map[string]Host
 // Maps Node Key -> Map of Query Name -> Query struct
 var queryMap map[string]map[string]Query
 
+// Handlers run concurrently so guard both enrolledHosts and queryMap
+var queryMutex sync.Mutex
+
 // API Request Struct
 type apiRequest struct {
 	NodeKey string `json:"node_key"`
@@ -119,12 +125,16 @@ func enroll(w http.ResponseWriter, r *http.Request) {
 	if parsedBody.HostIdentifier != "" {
 		newHost.UUID = parsedBody.HostIdentifier
 	}
+	queryMutex.Lock()
 	enrolledHosts[nodeKey] = newHost
 	queryMap[nodeKey] = make(map[string]Query)
-	fmt.Printf("Enrolled a host (%s) with node_key: %s\n", enrolledHosts[nodeKey].UUID, nodeKey)
+	queryMutex.Unlock()
+	fmt.Printf("Enrolled a host (%s) with node_key: %s\n", newHost.UUID, nodeKey)
 }
 
 func isNodeKeyEnrolled(ar apiRequest) bool {
+	queryMutex.Lock()
+	defer queryMutex.Unlock()
 	if _, ok := enrolledHosts[ar.NodeKey]; !ok {
 		return false
 	}
@@ -179,6 +189,8 @@ func distributedRead(w http.ResponseWriter, r *http.Request) {
 
 	// The check below should never fail. If it does we've really screwed up
 	renderedQueries := ""
+	queryMutex.Lock()
+	defer queryMutex.Unlock()
 	if _, ok := queryMap[parsedRequest.NodeKey]; !ok {
 		fmt.Fprintf(w, "{\"node_invalid\" : true}")
 		fmt.Printf("This should never occur. A host is enrolled but not configured for distributed\n")
@@ -209,6 +221,7 @@ func distributedWrite(w http.ResponseWriter, r *http.Request) {
 	type distributedResponse struct {
 		Queries  map[string]json.RawMessage `json:"queries"`
 		Statuses map[string]int             `json:"statuses"`
+		Messages map[string]string          `json:"messages"`
 		NodeKey  string                     `json:"node_key"`
 	}
 
@@ -230,8 +243,11 @@ func distributedWrite(w http.ResponseWriter, r *http.Request) {
 	type responseQuery struct {
 		Rows     json.RawMessage
 		Status   string
+		Message  string
 		SQLQuery string
 	}
+	queryMutex.Lock()
+	defer queryMutex.Unlock()
 	responses := make(map[string]*responseQuery)
 	for queryName, resultsRaw := range responseParsed.Queries {
 		sqlQuery := queryMap[responseParsed.NodeKey][queryName].Query
@@ -243,8 +259,13 @@ func distributedWrite(w http.ResponseWriter, r *http.Request) {
 	for queryName, statusCode := range responseParsed.Statuses {
 		if statusCode == 0 {
 			responses[queryName].Status = "Complete"
-		} else {
-			responses[queryName].Status = fmt.Sprintf("Status Code %d", statusCode)
+			continue
+		}
+		// osquery sends the error for a failed query in messages when it has one
+		responses[queryName].Status = "Failed"
+		responses[queryName].Message = responseParsed.Messages[queryName]
+		if responses[queryName].Message == "" {
+			responses[queryName].Message = fmt.Sprintf("Status Code %d", statusCode)
 		}
 	}
 
@@ -255,6 +276,7 @@ func distributedWrite(w http.ResponseWriter, r *http.Request) {
 			Complete: true,
 			Result:   response.Rows,
 			Status:   response.Status,
+			Message:  response.Message,
 		}
 		fmt.Printf("Received and set query results for %s\n", queryName)
 	}
@@ -263,6 +285,8 @@ func distributedWrite(w http.ResponseWriter, r *http.Request) {
 // End osquery API endpoints
 
 func checkHostExists(requestedUUID string) (string, error) {
+	queryMutex.Lock()
+	defer queryMutex.Unlock()
 	for nodeKey, host := range enrolledHosts {
 		if host.UUID == requestedUUID {
 			return nodeKey, nil
@@ -282,7 +306,9 @@ func checkHost(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	queryMutex.Lock()
 	renderedHost, err := json.Marshal(enrolledHosts[nodeKey])
+	queryMutex.Unlock()
 	if err != nil {
 		w.WriteHeader(http.StatusInternalServerError)
 		return
@@ -292,18 +318,24 @@ func checkHost(w http.ResponseWriter, r *http.Request) {
 
 func scheduleQuery(w http.ResponseWriter, r *http.Request) {
 	uuid := r.FormValue("uuid")
-	sentQuery, err := json.Marshal(r.FormValue("query"))
-
+	fmt.Printf("ScheduleQuery call for: %s with query: %s\n", uuid, r.FormValue("query"))
+	queryName, err := queueQuery(uuid, r.FormValue("query"))
 	if err != nil {
-		w.WriteHeader(http.StatusInternalServerError)
+		w.WriteHeader(http.StatusNotFound)
 		return
 	}
+	fmt.Fprintf(w, "{\"queryName\" : \"%s\"}", queryName)
+}
 
-	fmt.Printf("ScheduleQuery call for: %s with query: %s\n", uuid, string(sentQuery))
+// queueQuery adds a query for the host with uuid to pick up on its next distributed read
+func queueQuery(uuid string, sql string) (string, error) {
+	sentQuery, err := json.Marshal(sql)
+	if err != nil {
+		return "", err
+	}
 	nodeKey, err := checkHostExists(uuid)
 	if err != nil {
-		w.WriteHeader(http.StatusNotFound)
-		return
+		return "", err
 	}
 	query := Query{
 		Name:   randomString(64),
@@ -311,8 +343,10 @@ func scheduleQuery(w http.ResponseWriter, r *http.Request) {
 		Status: "Pending",
 	}
 
+	queryMutex.Lock()
 	queryMap[nodeKey][query.Name] = query
-	fmt.Fprintf(w, "{\"queryName\" : \"%s\"}", query.Name)
+	queryMutex.Unlock()
+	return query.Name, nil
 }
 
 func fetchResults(w http.ResponseWriter, r *http.Request) {
@@ -321,21 +355,60 @@ func fetchResults(w http.ResponseWriter, r *http.Request) {
 	// Yes I know this is really slow. For testing it should be fine
 	// but I will fix this architecture later if needed
 	// The real solution will be to use a better backing store like postgres
-	for _, queries := range queryMap {
-		if query, ok := queries[queryName]; ok {
-			bytes, err := json.MarshalIndent(&query, "", "\t")
+	if query, ok := lookupQuery(queryName); ok {
+		if r.FormValue("limit") != "" {
+			paged, err := pageResults(query.Result, r.FormValue("offset"), r.FormValue("limit"))
 			if err != nil {
-				fmt.Printf("Could not encode query result: %s\n", err)
-				fmt.Fprintf(w, "Could not encode query result: %s\n", err)
-				w.WriteHeader(http.StatusInternalServerError)
+				fmt.Printf("Could not page query result: %s\n", err)
+				w.WriteHeader(http.StatusBadRequest)
+				return
 			}
-			w.Write(bytes)
-			return
+			query.Result = paged
+		}
+		bytes, err := json.MarshalIndent(&query, "", "\t")
+		if err != nil {
+			fmt.Printf("Could not encode query result: %s\n", err)
+			fmt.Fprintf(w, "Could not encode query result: %s\n", err)
+			w.WriteHeader(http.StatusInternalServerError)
 		}
+		w.Write(bytes)
+		return
 	}
 	w.WriteHeader(http.StatusNotFound)
 }
 
+// pageResults returns at most limit rows from results starting at offset
+func pageResults(results json.RawMessage, offsetValue, limitValue string) (json.RawMessage, error) {
+	offset, err := strconv.Atoi(offsetValue)
+	if offsetValue == "" {
+		offset, err = 0, nil
+	}
+	if err != nil || offset < 0 {
+		return nil, fmt.Errorf("Invalid offset: %s", offsetValue)
+	}
+	limit, err := strconv.Atoi(limitValue)
+	if err != nil || limit < 0 {
+		return nil, fmt.Errorf("Invalid limit: %s", limitValue)
+	}
+	// Pending queries have no results yet
+	if len(results) == 0 {
+		return results, nil
+	}
+
+	rows := []json.RawMessage{}
+	if err := json.Unmarshal(results, &rows); err != nil {
+		return nil, err
+	}
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	end := offset + limit
+	if end > len(rows) {
+		end = len(rows)
+	}
+	return json.Marshal(rows[offset:end])
+}
+
 // End goquery APIs
 
 func doPut(url string, metadata string) error {
@@ -355,6 +428,8 @@ func main() {
 	enableSSO := true
 	enrolledHosts = make(map[string]Host)
 	queryMap = make(map[string]map[string]Query)
+	carveSessions = make(map[string]*carveSession)
+	carveRequests = make(map[string]string)
 
 	// Set up flags for certs
 	serverCrt := flag.String("server_cert", "certs/example_server.crt", "Location of a certificate to use")
@@ -371,6 +446,8 @@ func main() {
 	http.HandleFunc("/log", log)
 	http.HandleFunc("/distributedRead", distributedRead)
 	http.HandleFunc("/distributedWrite", distributedWrite)
+	http.HandleFunc("/start_uploads", startUploads)
+	http.HandleFunc("/upload_blocks", uploadBlocks)
 
 	// goquery Endpoints
 	if enableSSO {
@@ -427,11 +504,17 @@ func main() {
 		http.Handle("/checkHost", samlSP.RequireAccount(ch))
 		http.Handle("/scheduleQuery", samlSP.RequireAccount(sq))
 		http.Handle("/fetchResults", samlSP.RequireAccount(fr))
+		http.Handle("/startCarve", samlSP.RequireAccount(http.HandlerFunc(startCarve)))
+		http.Handle("/carveStatus", samlSP.RequireAccount(http.HandlerFunc(carveStatus)))
+		http.Handle("/carveBlock", samlSP.RequireAccount(http.HandlerFunc(carveBlock)))
 		http.Handle("/saml/", samlSP)
 	} else {
 		http.HandleFunc("/checkHost", checkHost)
 		http.HandleFunc("/scheduleQuery", scheduleQuery)
 		http.HandleFunc("/fetchResults", fetchResults)
+		http.HandleFunc("/startCarve", startCarve)
+		http.HandleFunc("/carveStatus", carveStatus)
+		http.HandleFunc("/carveBlock", carveBlock)
 	}
 	fmt.Printf("Starting test goquery/osquery backend...\n")
 	fmt.Printf("Server Cert Path: %s\n", *serverCrt)
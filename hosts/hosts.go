@@ -1,11 +1,12 @@
 // Package hosts is responsible for holding the state of which hosts
-// the goquery shell is currently connected to. The state should
-// only be mutated via the .connect or .switch commands, but can be
-// looked up anywhere.
+// a goquery session is currently connected to. Each session owns its
+// own Registry, the state should only be mutated via the .connect or
+// .switch commands, but can be looked up anywhere.
 package hosts
 
 import (
 	"fmt"
+	"sync"
 )
 
 type Query struct {
@@ -13,6 +14,12 @@ type Query struct {
 	SQL  string
 }
 
+// Column is a single column of a table on a host
+type Column struct {
+	Name string
+	Type string
+}
+
 type Host struct {
 	UUID             string
 	ComputerName     string
@@ -22,6 +29,8 @@ type Host struct {
 	CurrentDirectory string
 	Username         string
 	Tables           []string
+	// Columns holds the schema of each table that has been looked up so far
+	Columns map[string][]Column
 }
 
 func (host *Host) SetCurrentDirectory(newDirectory string) error {
@@ -35,111 +44,187 @@ func (host *Host) SetCurrentDirectory(newDirectory string) error {
 	return nil
 }
 
-var currentHostIndex int
-var connectedHosts []Host
+// Registry holds the hosts a single goquery session is connected to
+// and which of them is the current host. It is safe for concurrent use,
+// every Host returned from it is a snapshot that can be read without locking.
+type Registry struct {
+	mutex            sync.RWMutex
+	currentHostIndex int
+	connectedHosts   []Host
+}
 
-func init() {
-	currentHostIndex = -1
-	connectedHosts = []Host{}
+// NewRegistry creates an empty Registry with no current host
+func NewRegistry() *Registry {
+	return &Registry{
+		currentHostIndex: -1,
+		connectedHosts:   []Host{},
+	}
+}
+
+// snapshot copies a host including its slices so it can be handed out
+// without sharing memory with the registry
+func (host Host) snapshot() Host {
+	host.QueryHistory = append([]Query(nil), host.QueryHistory...)
+	host.Tables = append([]string(nil), host.Tables...)
+	columns := make(map[string][]Column, len(host.Columns))
+	for table, tableColumns := range host.Columns {
+		columns[table] = append([]Column(nil), tableColumns...)
+	}
+	host.Columns = columns
+	return host
+}
+
+// indexOf returns the index of the host with uuid, or -1. The caller must hold the lock.
+func (registry *Registry) indexOf(uuid string) int {
+	for i, host := range registry.connectedHosts {
+		if uuid == host.UUID {
+			return i
+		}
+	}
+	return -1
 }
 
 // Register is responsible for adding a host to the list
 // of established connected hosts in the host list. Also
 // update the cursor of the current connected host.
 // If a given host is already in the list, return the index
-func Register(newHost Host) error {
-	for i, host := range connectedHosts {
-		if newHost.UUID == host.UUID {
-			currentHostIndex = i
-			return nil
-		}
+func (registry *Registry) Register(newHost Host) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if i := registry.indexOf(newHost.UUID); i != -1 {
+		registry.currentHostIndex = i
+		return nil
 	}
-	connectedHosts = append(connectedHosts, newHost)
-	currentHostIndex = len(connectedHosts) - 1
+	registry.connectedHosts = append(registry.connectedHosts, newHost.snapshot())
+	registry.currentHostIndex = len(registry.connectedHosts) - 1
 	return nil
 }
 
 // Disconnect is responsible for removing a host from the list
 // Can be called with a specific host uuid or an empty "" to
 // denote the current host the cursor is on
-func Disconnect(uuid string) error {
-	index := -1
-	if uuid == "" {
-		index = currentHostIndex
-	} else {
-		// find provided uuid index in list
-		for i, host := range connectedHosts {
-			if uuid == host.UUID {
-				index = i
-				break
-			}
-		}
+func (registry *Registry) Disconnect(uuid string) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	index := registry.currentHostIndex
+	if uuid != "" {
+		index = registry.indexOf(uuid)
 	}
 	if index == -1 {
 		return fmt.Errorf("No active host connection with uuid %s", uuid)
 	}
 	// Remove found host index from list of connected hosts
-	connectedHosts = append(connectedHosts[:index], connectedHosts[index+1:]...)
-	currentHostIndex = -1
+	registry.connectedHosts = append(registry.connectedHosts[:index], registry.connectedHosts[index+1:]...)
+	registry.currentHostIndex = -1
 	return nil
 }
 
 // SetCurrentHost updates the current index used to fetch
 // the uuid of GetCurrentHost's call, returns the uuid
-func SetCurrentHost(targetIndex int) (string, error) {
-	if targetIndex >= 0 && targetIndex < len(connectedHosts) {
-		currentHostIndex = targetIndex
-		return connectedHosts[currentHostIndex].UUID, nil
+func (registry *Registry) SetCurrentHost(targetIndex int) (string, error) {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if targetIndex >= 0 && targetIndex < len(registry.connectedHosts) {
+		registry.currentHostIndex = targetIndex
+		return registry.connectedHosts[registry.currentHostIndex].UUID, nil
 	}
-	return "", fmt.Errorf("Index out of range, currently connected to %d host(s)", len(connectedHosts))
+	return "", fmt.Errorf("Index out of range, currently connected to %d host(s)", len(registry.connectedHosts))
 }
 
-// GetCurrentHost is a public API that returns a point to the current host structure.
-func GetCurrentHost() (Host, error) {
-	if len(connectedHosts) == 0 {
+// GetCurrentHost is a public API that returns a snapshot of the current host structure.
+func (registry *Registry) GetCurrentHost() (Host, error) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	if len(registry.connectedHosts) == 0 {
 		return Host{}, fmt.Errorf("No active host connections")
 	}
 
-	if currentHostIndex == -1 {
+	if registry.currentHostIndex == -1 {
 		return Host{}, fmt.Errorf("No host index set")
 	}
-	return connectedHosts[currentHostIndex], nil
+	return registry.connectedHosts[registry.currentHostIndex].snapshot(), nil
 }
 
-func SetCurrentHostDirectory(newDirectory string) error {
-	if len(connectedHosts) == 0 {
+// GetHost returns a snapshot of the connected host with uuid
+func (registry *Registry) GetHost(uuid string) (Host, error) {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	index := registry.indexOf(uuid)
+	if index == -1 {
+		return Host{}, fmt.Errorf("No active host connection with uuid %s", uuid)
+	}
+	return registry.connectedHosts[index].snapshot(), nil
+}
+
+func (registry *Registry) SetCurrentHostDirectory(newDirectory string) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	if len(registry.connectedHosts) == 0 {
 		return fmt.Errorf("No active host connections")
 	}
 
-	if currentHostIndex == -1 {
+	if registry.currentHostIndex == -1 {
 		return fmt.Errorf("No host index set")
 	}
-	return connectedHosts[currentHostIndex].SetCurrentDirectory(newDirectory)
+	return registry.connectedHosts[registry.currentHostIndex].SetCurrentDirectory(newDirectory)
 }
 
-func SetHostTables(uuid string, tables []string) {
-	for index, _ := range connectedHosts {
-		if connectedHosts[index].UUID != uuid {
-			continue
-		}
-		connectedHosts[index].Tables = tables
-		return
+// SetHostTables replaces the list of tables available on a connected host
+func (registry *Registry) SetHostTables(uuid string, tables []string) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	index := registry.indexOf(uuid)
+	if index == -1 {
+		return fmt.Errorf("Cannot set tables, no active host connection with uuid %s", uuid)
 	}
-	panic("Setting Tables On Unconnected Host!! Something is very wrong!")
+	registry.connectedHosts[index].Tables = append([]string(nil), tables...)
+	return nil
 }
 
-func AddQueryToHost(uuid string, newQuery Query) {
-	for index, _ := range connectedHosts {
-		if connectedHosts[index].UUID != uuid {
-			continue
-		}
-		connectedHosts[index].QueryHistory = append(connectedHosts[index].QueryHistory, newQuery)
-		return
+// SetHostTableColumns records the columns of one table on a connected host
+func (registry *Registry) SetHostTableColumns(uuid string, table string, columns []Column) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	index := registry.indexOf(uuid)
+	if index == -1 {
+		return fmt.Errorf("Cannot set columns, no active host connection with uuid %s", uuid)
 	}
-	panic("Query Ran On Unconnected Host!! Something is very wrong!")
+	if registry.connectedHosts[index].Columns == nil {
+		registry.connectedHosts[index].Columns = make(map[string][]Column)
+	}
+	registry.connectedHosts[index].Columns[table] = append([]Column(nil), columns...)
+	return nil
+}
+
+// AddQueryToHost appends a query to a connected host's query history
+func (registry *Registry) AddQueryToHost(uuid string, newQuery Query) error {
+	registry.mutex.Lock()
+	defer registry.mutex.Unlock()
+
+	index := registry.indexOf(uuid)
+	if index == -1 {
+		return fmt.Errorf("Cannot record query, no active host connection with uuid %s", uuid)
+	}
+	registry.connectedHosts[index].QueryHistory = append(registry.connectedHosts[index].QueryHistory, newQuery)
+	return nil
 }
 
-// GetCurrentHosts is a public API that returns a the current state of the connectedHosts array
-func GetCurrentHosts() []Host {
-	return connectedHosts
+// GetCurrentHosts is a public API that returns a snapshot of the connected hosts
+func (registry *Registry) GetCurrentHosts() []Host {
+	registry.mutex.RLock()
+	defer registry.mutex.RUnlock()
+
+	hosts := make([]Host, len(registry.connectedHosts))
+	for i, host := range registry.connectedHosts {
+		hosts[i] = host.snapshot()
+	}
+	return hosts
 }
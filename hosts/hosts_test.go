@@ -0,0 +1,96 @@
+package hosts
+
+import (
+	"fmt"
+	"sync"
+	"testing"
+)
+
+func TestRegistryConcurrentUse(t *testing.T) {
+	registry := NewRegistry()
+	var wait sync.WaitGroup
+	for i := 0; i < 20; i++ {
+		wait.Add(1)
+		go func(i int) {
+			defer wait.Done()
+			uuid := fmt.Sprintf("host-%d", i%5)
+			for j := 0; j < 100; j++ {
+				registry.Register(Host{UUID: uuid, CurrentDirectory: "/"})
+				registry.SetCurrentHost(j % 5)
+				registry.AddQueryToHost(uuid, Query{Name: fmt.Sprintf("%d-%d", i, j)})
+				registry.SetHostTables(uuid, []string{"processes"})
+				registry.SetHostTableColumns(uuid, "processes", []Column{{Name: "pid", Type: "BIGINT"}})
+				registry.SetCurrentHostDirectory("/tmp/")
+				if host, err := registry.GetHost(uuid); err == nil {
+					// Snapshots are private copies, writing to one must not race with the registry
+					host.Tables[0] = "changed"
+					host.Columns["processes"] = nil
+				}
+				registry.GetCurrentHost()
+				for _, host := range registry.GetCurrentHosts() {
+					_ = len(host.QueryHistory)
+				}
+			}
+		}(i)
+	}
+	wait.Wait()
+
+	connected := registry.GetCurrentHosts()
+	if len(connected) != 5 {
+		t.Fatalf("expected 5 connected hosts, got %d", len(connected))
+	}
+	queries := 0
+	for _, host := range connected {
+		queries += len(host.QueryHistory)
+		if host.Tables[0] != "processes" || len(host.Columns["processes"]) != 1 {
+			t.Fatalf("a snapshot write leaked into the registry: %+v", host)
+		}
+	}
+	if queries != 20*100 {
+		t.Fatalf("expected %d queries in history, got %d", 20*100, queries)
+	}
+}
+
+func TestRegistryUnknownHostErrors(t *testing.T) {
+	registry := NewRegistry()
+	// AddQueryToHost and SetHostTables used to panic for an unconnected host, now
+	// every call that needs a host returns an error instead
+	if err := registry.AddQueryToHost("missing", Query{Name: "q"}); err == nil {
+		t.Error("AddQueryToHost on an unconnected host should fail")
+	}
+	if err := registry.SetHostTables("missing", []string{"processes"}); err == nil {
+		t.Error("SetHostTables on an unconnected host should fail")
+	}
+	if err := registry.SetHostTableColumns("missing", "processes", nil); err == nil {
+		t.Error("SetHostTableColumns on an unconnected host should fail")
+	}
+	if _, err := registry.GetCurrentHost(); err == nil {
+		t.Error("GetCurrentHost without hosts should fail")
+	}
+	if err := registry.SetCurrentHostDirectory("/"); err == nil {
+		t.Error("SetCurrentHostDirectory without hosts should fail")
+	}
+	if err := registry.Disconnect(""); err == nil {
+		t.Error("Disconnect without a current host should fail")
+	}
+
+	registry.Register(Host{UUID: "a"})
+	if _, err := registry.SetCurrentHost(1); err == nil {
+		t.Error("SetCurrentHost past the connected hosts should fail")
+	}
+	if _, err := registry.SetCurrentHost(-1); err == nil {
+		t.Error("SetCurrentHost with a negative index should fail")
+	}
+	if err := registry.Disconnect("missing"); err == nil {
+		t.Error("Disconnect of an unconnected host should fail")
+	}
+	if err := registry.Disconnect(""); err != nil {
+		t.Fatal(err)
+	}
+	// Disconnecting leaves no current host rather than a stale index
+	registry.Register(Host{UUID: "b"})
+	registry.Disconnect("b")
+	if _, err := registry.GetCurrentHost(); err == nil {
+		t.Error("GetCurrentHost after disconnecting the current host should fail")
+	}
+}
@@ -0,0 +1,89 @@
+// Package apitest is a fake goquery API for tests. Every host exists and every query is
+// answered immediately with the rows registered for its SQL.
+package apitest
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// API implements models.GoQueryAPI. A query without registered rows fails like a missing
+// table would.
+type API struct {
+	mutex   sync.Mutex
+	results map[string]models.Rows
+	queries map[string]string
+	// scheduled lists the SQL of every query scheduled in order
+	scheduled []string
+	checked   int
+}
+
+// New returns an API answering each query in results with its rows
+func New(results map[string]models.Rows) *API {
+	if results == nil {
+		results = make(map[string]models.Rows)
+	}
+	return &API{results: results, queries: make(map[string]string)}
+}
+
+// CheckHost finds any host, named "host-" followed by its UUID
+func (api *API) CheckHost(uuid string) (hosts.Host, error) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.checked++
+	return hosts.Host{UUID: uuid, ComputerName: "host-" + uuid, CurrentDirectory: "/"}, nil
+}
+
+func (api *API) ScheduleQuery(uuid string, query string) (string, error) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.scheduled = append(api.scheduled, query)
+	name := fmt.Sprintf("query-%d", len(api.scheduled))
+	api.queries[name] = query
+	return name, nil
+}
+
+func (api *API) FetchResults(queryName string) (models.Rows, models.QueryStatus, error) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	query, ok := api.queries[queryName]
+	if !ok {
+		return models.Rows{}, models.QueryStatus{State: models.QueryUnknown}, models.ErrQueryNotFound
+	}
+	rows, ok := api.results[query]
+	if !ok {
+		return models.Rows{}, models.QueryStatus{State: models.QueryFailed, Message: "no such table"}, nil
+	}
+	return rows, models.QueryStatus{State: models.QueryComplete}, nil
+}
+
+// SetResults answers query with rows from now on
+func (api *API) SetResults(query string, rows models.Rows) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	api.results[query] = rows
+}
+
+// RemoveResults makes query fail from now on
+func (api *API) RemoveResults(query string) {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	delete(api.results, query)
+}
+
+// Scheduled returns the SQL of every query scheduled so far in order
+func (api *API) Scheduled() []string {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	return append([]string(nil), api.scheduled...)
+}
+
+// Checked is the number of CheckHost calls so far
+func (api *API) Checked() int {
+	api.mutex.Lock()
+	defer api.mutex.Unlock()
+	return api.checked
+}
@@ -0,0 +1,308 @@
+// Package jobs tracks queries that were scheduled without waiting for their
+// results. Every job is polled in the background until it completes, fails,
+// expires or is cancelled, and an optional notify function is told when that
+// happens.
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// Job states
+const (
+	StatusPending   = "Pending"
+	StatusComplete  = "Complete"
+	StatusFailed    = "Failed"
+	StatusExpired   = "Expired"
+	StatusCancelled = "Cancelled"
+)
+
+// Polling backs off from minPollInterval, doubling up to maxPollInterval
+const (
+	minPollInterval = time.Second
+	maxPollInterval = 30 * time.Second
+)
+
+// Finished jobs are forgotten, with their rows, once they are older than
+// finishedJobRetention or more than maxFinishedJobs newer jobs have finished
+const (
+	finishedJobRetention = time.Hour
+	maxFinishedJobs      = 100
+)
+
+// Job is a snapshot of a single tracked query
+type Job struct {
+	Name     string
+	Host     hosts.Host
+	SQL      string
+	Started  time.Time
+	Finished time.Time
+	Status   string
+	Rows     models.Rows
+	Err      error
+}
+
+// Duration is how long the job ran for, or has been running for if still pending
+func (job Job) Duration() time.Duration {
+	if job.Finished.IsZero() {
+		return time.Since(job.Started)
+	}
+	return job.Finished.Sub(job.Started)
+}
+
+type trackedJob struct {
+	job     Job
+	waiting int
+	// waited is whether anyone was waiting on the job when it finished
+	waited bool
+	done   chan struct{}
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Manager polls every tracked job in the background. It is safe for concurrent use.
+type Manager struct {
+	api      models.GoQueryAPIContext
+	deadline time.Duration
+	notify   func(Job, bool)
+
+	mutex sync.RWMutex
+	jobs  map[string]*trackedJob
+	order []string
+}
+
+// NewManager creates a Manager that polls results through api. Jobs still pending after
+// deadline expire. If notify is not nil it is called from the polling goroutine whenever a
+// job finishes, waited reports whether anyone was waiting on the job at the time.
+func NewManager(api models.GoQueryAPI, deadline time.Duration, notify func(job Job, waited bool)) *Manager {
+	return &Manager{
+		api:      models.WithContext(api),
+		deadline: deadline,
+		notify:   notify,
+		jobs:     make(map[string]*trackedJob),
+		order:    []string{},
+	}
+}
+
+// Track starts polling for the results of a query that was already scheduled on host
+func (manager *Manager) Track(host hosts.Host, queryName, sql string) {
+	ctx, cancel := context.WithCancel(context.Background())
+	tracked := &trackedJob{
+		job: Job{
+			Name:    queryName,
+			Host:    host,
+			SQL:     sql,
+			Started: time.Now(),
+			Status:  StatusPending,
+		},
+		done:   make(chan struct{}),
+		ctx:    ctx,
+		cancel: cancel,
+	}
+
+	manager.mutex.Lock()
+	manager.evictLocked()
+	if existing, exists := manager.jobs[queryName]; exists {
+		// The name now belongs to the new job, stop polling for the old one and release
+		// anything still waiting on it
+		manager.finishLocked(existing, StatusCancelled, nil, nil)
+	} else {
+		manager.order = append(manager.order, queryName)
+	}
+	manager.jobs[queryName] = tracked
+	manager.mutex.Unlock()
+
+	go manager.poll(tracked)
+}
+
+func (manager *Manager) poll(tracked *trackedJob) {
+	expires := time.Now().Add(manager.deadline)
+	interval := minPollInterval
+	for {
+		wait := interval
+		if remaining := time.Until(expires); remaining < wait {
+			wait = remaining
+		}
+		select {
+		case <-tracked.ctx.Done():
+			return
+		case <-time.After(wait):
+		}
+
+		rows, status, err := manager.api.FetchResultsContext(tracked.ctx, tracked.job.Name)
+		if tracked.ctx.Err() != nil {
+			return
+		}
+		if err == nil && status.Pending() {
+			if !time.Now().Before(expires) {
+				manager.finishAndNotify(tracked, StatusExpired, nil, fmt.Errorf("No results after %s: %w", manager.deadline, models.ErrQueryExpired))
+				return
+			}
+			interval *= 2
+			if interval > maxPollInterval {
+				interval = maxPollInterval
+			}
+			continue
+		}
+
+		if err == nil {
+			err = status.Err()
+		}
+		finalStatus := StatusComplete
+		if errors.Is(err, models.ErrQueryExpired) {
+			finalStatus = StatusExpired
+		} else if err != nil {
+			finalStatus = StatusFailed
+		}
+		manager.finishAndNotify(tracked, finalStatus, rows, err)
+		return
+	}
+}
+
+// finishAndNotify finishes a job from its polling goroutine and tells notify about it
+func (manager *Manager) finishAndNotify(tracked *trackedJob, status string, rows models.Rows, err error) {
+	if !manager.finish(tracked, status, rows, err) {
+		return
+	}
+	// Neither field changes once the job has finished
+	manager.mutex.RLock()
+	snapshot, waited := tracked.job, tracked.waited
+	manager.mutex.RUnlock()
+	if manager.notify != nil {
+		manager.notify(snapshot, waited)
+	}
+}
+
+// finish records the final state of a job, returning false if it had already finished
+func (manager *Manager) finish(tracked *trackedJob, status string, rows models.Rows, err error) bool {
+	manager.mutex.Lock()
+	defer manager.mutex.Unlock()
+	return manager.finishLocked(tracked, status, rows, err)
+}
+
+// finishLocked is finish for a caller already holding the lock
+func (manager *Manager) finishLocked(tracked *trackedJob, status string, rows models.Rows, err error) bool {
+	if tracked.job.Status != StatusPending {
+		return false
+	}
+	tracked.job.Status = status
+	tracked.job.Rows = rows
+	tracked.job.Err = err
+	tracked.job.Finished = time.Now()
+	tracked.waited = tracked.waiting > 0
+	close(tracked.done)
+	tracked.cancel()
+	return true
+}
+
+// evictLocked forgets finished jobs older than finishedJobRetention and all but the
+// maxFinishedJobs most recently finished ones, the caller must hold the lock
+func (manager *Manager) evictLocked() {
+	// Newest first, ties keep the most recently started job first
+	finished := make([]*trackedJob, 0)
+	for i := len(manager.order) - 1; i >= 0; i-- {
+		if tracked := manager.jobs[manager.order[i]]; tracked.job.Status != StatusPending {
+			finished = append(finished, tracked)
+		}
+	}
+	sort.SliceStable(finished, func(i, j int) bool {
+		return finished[i].job.Finished.After(finished[j].job.Finished)
+	})
+	evicted := make(map[string]bool)
+	for i, tracked := range finished {
+		if i >= maxFinishedJobs || time.Since(tracked.job.Finished) > finishedJobRetention {
+			evicted[tracked.job.Name] = true
+			delete(manager.jobs, tracked.job.Name)
+		}
+	}
+	if len(evicted) == 0 {
+		return
+	}
+	order := make([]string, 0, len(manager.jobs))
+	for _, name := range manager.order {
+		if !evicted[name] {
+			order = append(order, name)
+		}
+	}
+	manager.order = order
+}
+
+func (manager *Manager) lookup(name string) (*trackedJob, error) {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	tracked, ok := manager.jobs[name]
+	if !ok {
+		return nil, fmt.Errorf("No such job: %s", name)
+	}
+	return tracked, nil
+}
+
+// Get returns a snapshot of the job with name
+func (manager *Manager) Get(name string) (Job, error) {
+	tracked, err := manager.lookup(name)
+	if err != nil {
+		return Job{}, err
+	}
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	return tracked.job, nil
+}
+
+// Jobs returns a snapshot of every tracked job in the order they were started
+func (manager *Manager) Jobs() []Job {
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	jobs := make([]Job, 0, len(manager.order))
+	for _, name := range manager.order {
+		jobs = append(jobs, manager.jobs[name].job)
+	}
+	return jobs
+}
+
+// Wait blocks until the job with name is no longer pending or ctx is done
+func (manager *Manager) Wait(ctx context.Context, name string) (Job, error) {
+	tracked, err := manager.lookup(name)
+	if err != nil {
+		return Job{}, err
+	}
+
+	manager.mutex.Lock()
+	tracked.waiting++
+	manager.mutex.Unlock()
+	defer func() {
+		manager.mutex.Lock()
+		tracked.waiting--
+		manager.mutex.Unlock()
+	}()
+
+	select {
+	case <-tracked.done:
+	case <-ctx.Done():
+		return Job{}, fmt.Errorf("Waiting Cancelled")
+	}
+	// The job may have been evicted or replaced since, so it is read from tracked
+	manager.mutex.RLock()
+	defer manager.mutex.RUnlock()
+	return tracked.job, nil
+}
+
+// Cancel stops polling for a pending job, abandoning any in flight request. The query
+// itself is not recalled from the host since the backend has no way to do so.
+func (manager *Manager) Cancel(name string) error {
+	tracked, err := manager.lookup(name)
+	if err != nil {
+		return err
+	}
+	if !manager.finish(tracked, StatusCancelled, nil, nil) {
+		return fmt.Errorf("Job %s has already finished", name)
+	}
+	return nil
+}
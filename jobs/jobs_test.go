@@ -0,0 +1,105 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// pendingAPI reports every query as still running
+type pendingAPI struct{}
+
+func (pendingAPI) CheckHost(uuid string) (hosts.Host, error) {
+	return hosts.Host{UUID: uuid}, nil
+}
+
+func (pendingAPI) ScheduleQuery(uuid string, query string) (string, error) {
+	return "query", nil
+}
+
+func (pendingAPI) FetchResults(queryName string) (models.Rows, models.QueryStatus, error) {
+	return models.Rows{}, models.QueryStatus{State: models.QueryPending}, nil
+}
+
+func TestTrackReplacesJobWithSameName(t *testing.T) {
+	manager := NewManager(pendingAPI{}, time.Minute, nil)
+	manager.Track(hosts.Host{UUID: "a"}, "q", "select 1")
+	first, _ := manager.lookup("q")
+	manager.Track(hosts.Host{UUID: "a"}, "q", "select 2")
+
+	select {
+	case <-first.done:
+	case <-time.After(time.Second):
+		t.Fatal("the replaced job is still being tracked")
+	}
+	if first.ctx.Err() == nil || first.job.Status != StatusCancelled {
+		t.Fatalf("the replaced job was not cancelled: %+v", first.job)
+	}
+
+	jobs := manager.Jobs()
+	if len(jobs) != 1 || jobs[0].SQL != "select 2" || jobs[0].Status != StatusPending {
+		t.Fatalf("Jobs() = %+v", jobs)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if _, err := manager.Wait(ctx, "q"); err == nil {
+		t.Fatal("Wait should block on the new, still pending, job")
+	}
+	manager.Cancel("q")
+}
+
+func TestPendingJobsExpire(t *testing.T) {
+	notified := make(chan bool, 1)
+	manager := NewManager(pendingAPI{}, 50*time.Millisecond, func(job Job, waited bool) {
+		notified <- waited
+	})
+	manager.Track(hosts.Host{UUID: "a"}, "q", "select 1")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	job, err := manager.Wait(ctx, "q")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if job.Status != StatusExpired || !errors.Is(job.Err, models.ErrQueryExpired) {
+		t.Fatalf("a job pending past the deadline is %s: %v", job.Status, job.Err)
+	}
+	if waited := <-notified; !waited {
+		t.Fatal("the notice should know the job was waited on")
+	}
+}
+
+func TestFinishedJobsAreEvicted(t *testing.T) {
+	manager := NewManager(pendingAPI{}, time.Minute, nil)
+	for i := 0; i < maxFinishedJobs+5; i++ {
+		name := fmt.Sprintf("q%d", i)
+		manager.Track(hosts.Host{UUID: "a"}, name, "select 1")
+		manager.Cancel(name)
+	}
+	old, _ := manager.lookup("q10")
+	manager.mutex.Lock()
+	old.job.Finished = time.Now().Add(-finishedJobRetention - time.Second)
+	manager.mutex.Unlock()
+
+	manager.Track(hosts.Host{UUID: "a"}, "pending", "select 1")
+	defer manager.Cancel("pending")
+	// Each Track keeps the newest maxFinishedJobs finished jobs, so q0 to q3 went while
+	// they were being added and q10 has now gone for its age
+	jobs := manager.Jobs()
+	if len(jobs) != maxFinishedJobs+1 {
+		t.Fatalf("%d jobs kept, want %d finished and 1 pending", len(jobs), maxFinishedJobs)
+	}
+	for _, name := range []string{"q0", "q3", "q10"} {
+		if _, err := manager.Get(name); err == nil {
+			t.Errorf("%s should have been evicted", name)
+		}
+	}
+	if _, err := manager.Get("q4"); err != nil || jobs[len(jobs)-1].Name != "pending" {
+		t.Fatalf("the newest jobs should be kept: %v", err)
+	}
+}
@@ -1,14 +1,95 @@
 package models
 
 import (
+	"context"
+
 	"github.com/AbGuthrie/goquery/v2/hosts"
 )
 
 // GoQueryAPI defines the set of functions needed for goquery to interface with a backend
 // These functions also must handle any needed authentication because the rest of goquery
-// is blind to the implementation for code separation purposes.
+// is blind to the implementation for code separation purposes. Errors should wrap
+// ErrHostNotFound, ErrQueryNotFound and ErrUnauthenticated where they apply.
 type GoQueryAPI interface {
 	CheckHost(string) (hosts.Host, error)
 	ScheduleQuery(string, string) (string, error)
-	FetchResults(string) (Rows, string, error)
+	FetchResults(string) (Rows, QueryStatus, error)
+}
+
+// GoQueryAPIContext is the context aware version of GoQueryAPI. Implementations should
+// abandon any in flight request as soon as the provided context is cancelled.
+type GoQueryAPIContext interface {
+	GoQueryAPI
+	CheckHostContext(context.Context, string) (hosts.Host, error)
+	ScheduleQueryContext(context.Context, string, string) (string, error)
+	FetchResultsContext(context.Context, string) (Rows, QueryStatus, error)
+}
+
+// WithContext returns api as a GoQueryAPIContext. APIs that only implement GoQueryAPI
+// are adapted so callers stop waiting once the context is done, although the underlying
+// request cannot be interrupted and runs until it returns on its own.
+func WithContext(api GoQueryAPI) GoQueryAPIContext {
+	if contextAPI, ok := api.(GoQueryAPIContext); ok {
+		return contextAPI
+	}
+	return contextAdapter{api}
+}
+
+type contextAdapter struct {
+	GoQueryAPI
+}
+
+func (adapter contextAdapter) CheckHostContext(ctx context.Context, uuid string) (hosts.Host, error) {
+	type result struct {
+		host hosts.Host
+		err  error
+	}
+	done := make(chan result, 1)
+	go func() {
+		host, err := adapter.CheckHost(uuid)
+		done <- result{host, err}
+	}()
+	select {
+	case r := <-done:
+		return r.host, r.err
+	case <-ctx.Done():
+		return hosts.Host{}, ctx.Err()
+	}
+}
+
+func (adapter contextAdapter) ScheduleQueryContext(ctx context.Context, uuid string, query string) (string, error) {
+	type result struct {
+		queryName string
+		err       error
+	}
+	done := make(chan result, 1)
+	go func() {
+		queryName, err := adapter.ScheduleQuery(uuid, query)
+		done <- result{queryName, err}
+	}()
+	select {
+	case r := <-done:
+		return r.queryName, r.err
+	case <-ctx.Done():
+		return "", ctx.Err()
+	}
+}
+
+func (adapter contextAdapter) FetchResultsContext(ctx context.Context, queryName string) (Rows, QueryStatus, error) {
+	type result struct {
+		rows   Rows
+		status QueryStatus
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		rows, status, err := adapter.FetchResults(queryName)
+		done <- result{rows, status, err}
+	}()
+	select {
+	case r := <-done:
+		return r.rows, r.status, r.err
+	case <-ctx.Done():
+		return Rows{}, QueryStatus{State: QueryUnknown}, ctx.Err()
+	}
 }
@@ -0,0 +1,37 @@
+package models
+
+import "context"
+
+// CarveStatus is the progress of a file carve on a host
+type CarveStatus struct {
+	// Started is false until the host has begun uploading the carve
+	Started        bool
+	BlockCount     int
+	BlocksReceived int
+	// CarveSize is the size in bytes of the whole carve archive
+	CarveSize int64
+	// Error is set if the host reported that the carve failed
+	Error string
+}
+
+// Complete is true once every block of the carve has been uploaded
+func (status CarveStatus) Complete() bool {
+	return status.Started && status.BlocksReceived >= status.BlockCount
+}
+
+// FileCarver is an optional interface for APIs that can pull files from hosts with
+// osquery's carver. The carve archive is uploaded by the host to the backend in blocks
+// and goquery fetches the blocks once they have all arrived.
+type FileCarver interface {
+	// StartCarve asks the host with uuid to carve path and returns an ID for the carve
+	StartCarve(ctx context.Context, uuid string, path string) (string, error)
+	CarveStatus(ctx context.Context, carveID string) (CarveStatus, error)
+	// FetchCarveBlock returns a single block of the carve archive, numbered from 0
+	FetchCarveBlock(ctx context.Context, carveID string, blockID int) ([]byte, error)
+}
+
+// FileReader is an optional interface for APIs that can return the contents of a single
+// file on a host. APIs that only implement FileCarver are read through a carve instead.
+type FileReader interface {
+	ReadFile(ctx context.Context, uuid string, path string) ([]byte, error)
+}
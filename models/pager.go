@@ -0,0 +1,11 @@
+package models
+
+import "context"
+
+// GoQueryAPIPager is an optional interface for APIs that can return the results of a query
+// a page at a time instead of all at once. FetchResultsPage returns at most limit rows
+// starting at offset along with the status of the whole query. No rows are returned while
+// the query is pending, and a page with fewer than limit rows is the last one.
+type GoQueryAPIPager interface {
+	FetchResultsPage(ctx context.Context, queryName string, offset, limit int) (Rows, QueryStatus, error)
+}
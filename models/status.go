@@ -0,0 +1,72 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Errors returned by GoQueryAPI implementations, possibly wrapped with more detail.
+// Check for them with errors.Is.
+var (
+	ErrHostNotFound    = errors.New("Unknown Host")
+	ErrQueryNotFound   = errors.New("Unknown queryName")
+	ErrUnauthenticated = errors.New("Not authenticated")
+	ErrQueryFailed     = errors.New("Query failed")
+	ErrQueryExpired    = errors.New("Query expired before the host returned results")
+)
+
+// QueryState is where a scheduled query is in its lifecycle
+type QueryState string
+
+// Query states reported by FetchResults
+const (
+	QueryPending  QueryState = "Pending"
+	QueryComplete QueryState = "Complete"
+	QueryFailed   QueryState = "Failed"
+	QueryExpired  QueryState = "Expired"
+	QueryUnknown  QueryState = "Unknown"
+)
+
+// QueryStatus is the status of a scheduled query returned by FetchResults. When State
+// is QueryFailed, Message holds the error osquery reported for the query if there was one.
+type QueryStatus struct {
+	State   QueryState
+	Message string
+}
+
+// ParseQueryState maps a state name sent by a backend to a QueryState. Names that
+// are not recognised map to QueryUnknown.
+func ParseQueryState(name string) QueryState {
+	switch state := QueryState(name); state {
+	case QueryPending, QueryComplete, QueryFailed, QueryExpired:
+		return state
+	}
+	return QueryUnknown
+}
+
+// Pending is true while the host has not returned results yet
+func (status QueryStatus) Pending() bool {
+	return status.State == QueryPending
+}
+
+// Err returns an error wrapping ErrQueryFailed or ErrQueryExpired if the query did not
+// succeed, otherwise nil
+func (status QueryStatus) Err() error {
+	switch status.State {
+	case QueryFailed:
+		if status.Message == "" {
+			return ErrQueryFailed
+		}
+		return fmt.Errorf("%w: %s", ErrQueryFailed, status.Message)
+	case QueryExpired:
+		return ErrQueryExpired
+	}
+	return nil
+}
+
+func (status QueryStatus) String() string {
+	if status.Message == "" {
+		return string(status.State)
+	}
+	return fmt.Sprintf("%s (%s)", status.State, status.Message)
+}
@@ -0,0 +1,51 @@
+package models
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestParseQueryState(t *testing.T) {
+	tests := []struct {
+		name string
+		want QueryState
+	}{
+		{"Pending", QueryPending},
+		{"Complete", QueryComplete},
+		{"Failed", QueryFailed},
+		{"Expired", QueryExpired},
+		{"complete", QueryUnknown},
+		{"", QueryUnknown},
+	}
+	for _, test := range tests {
+		if got := ParseQueryState(test.name); got != test.want {
+			t.Errorf("ParseQueryState(%q) = %s, want %s", test.name, got, test.want)
+		}
+	}
+}
+
+func TestQueryStatusErr(t *testing.T) {
+	tests := []struct {
+		status  QueryStatus
+		wantErr error
+		text    string
+	}{
+		{QueryStatus{State: QueryComplete}, nil, "Complete"},
+		{QueryStatus{State: QueryPending}, nil, "Pending"},
+		{QueryStatus{State: QueryFailed}, ErrQueryFailed, "Failed"},
+		{QueryStatus{State: QueryFailed, Message: "no such table: x"}, ErrQueryFailed, "Failed (no such table: x)"},
+		{QueryStatus{State: QueryExpired}, ErrQueryExpired, "Expired"},
+	}
+	for _, test := range tests {
+		err := test.status.Err()
+		if (err == nil) != (test.wantErr == nil) || (err != nil && !errors.Is(err, test.wantErr)) {
+			t.Errorf("%v.Err() = %v, want %v", test.status, err, test.wantErr)
+		}
+		if test.status.String() != test.text {
+			t.Errorf("String() = %q, want %q", test.status.String(), test.text)
+		}
+	}
+	if err := (QueryStatus{State: QueryFailed, Message: "no such table: x"}).Err(); err.Error() != "Query failed: no such table: x" {
+		t.Errorf("a failed query's error should carry the osquery message, got %q", err)
+	}
+}
@@ -0,0 +1,104 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// directoryCacheTTL is how long a remote directory listing is reused for completion
+const directoryCacheTTL = time.Minute
+
+const directoryListingTemplate = "select filename, type from file where directory = '%s'"
+
+// DirectoryEntry is a single file in a remote directory listing, listings are sorted by name
+type DirectoryEntry struct {
+	Name        string
+	IsDirectory bool
+}
+
+type directoryListing struct {
+	entries []DirectoryEntry
+	fetched time.Time
+	pending bool
+}
+
+// directoryCache holds remote directory listings by host UUID and then directory
+type directoryCache struct {
+	mutex    sync.Mutex
+	listings map[string]map[string]*directoryListing
+}
+
+func newDirectoryCache() *directoryCache {
+	return &directoryCache{
+		listings: make(map[string]map[string]*directoryListing),
+	}
+}
+
+// CachedDirectory returns the entries of directory on the host with uuid, for use while
+// completing paths. Listings are fetched in the background so this never blocks on the
+// backend: ok is false until the first listing of a directory arrives, and a listing older
+// than directoryCacheTTL is still returned while a fresh one is fetched.
+func (s *Session) CachedDirectory(uuid, directory string) ([]DirectoryEntry, bool) {
+	cache := s.directories
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+
+	hostListings, ok := cache.listings[uuid]
+	if !ok {
+		hostListings = make(map[string]*directoryListing)
+		cache.listings[uuid] = hostListings
+	}
+	listing, ok := hostListings[directory]
+	if !ok {
+		listing = &directoryListing{}
+		hostListings[directory] = listing
+	}
+
+	if !listing.pending && time.Since(listing.fetched) > directoryCacheTTL {
+		listing.pending = true
+		go s.fetchDirectory(uuid, directory)
+	}
+	if listing.fetched.IsZero() {
+		return nil, false
+	}
+	return listing.entries, true
+}
+
+// fetchDirectory lists directory on the host with uuid and stores the result in the cache.
+// The query is not recorded in the host's query history.
+func (s *Session) fetchDirectory(uuid, directory string) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.Config.QueryDeadline())
+	defer cancel()
+
+	entries := make([]DirectoryEntry, 0)
+	query := fmt.Sprintf(directoryListingTemplate, strings.ReplaceAll(directory, "'", "''"))
+	queryName, err := s.ContextAPI().ScheduleQueryContext(ctx, uuid, query)
+	if err == nil {
+		results, _, waitErr := s.waitForResults(ctx, queryName, nil)
+		err = waitErr
+		for _, row := range results {
+			entries = append(entries, DirectoryEntry{
+				Name:        row["filename"],
+				IsDirectory: row["type"] == "directory",
+			})
+		}
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	cache := s.directories
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	listing := cache.listings[uuid][directory]
+	listing.pending = false
+	listing.fetched = time.Now()
+	// A failed fetch keeps any previous entries and isn't retried until the TTL passes,
+	// so completion doesn't schedule a query on every keystroke
+	if err == nil {
+		listing.entries = entries
+	}
+}
@@ -0,0 +1,121 @@
+package session
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/carve"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// fileCacheTTL is how long cached file contents are reused without checking the file
+// on the host again
+const fileCacheTTL = time.Minute
+
+const fileStatTemplate = "select size, type, mtime from file where path = '%s'"
+
+type cachedFile struct {
+	contents []byte
+	// size and mtime are as reported by the host when contents were read
+	size    string
+	mtime   string
+	checked time.Time
+}
+
+// fileCache holds remote file contents read in this session by host and then path
+type fileCache struct {
+	mutex sync.Mutex
+	files map[string]map[string]*cachedFile
+}
+
+func newFileCache() *fileCache {
+	return &fileCache{
+		files: make(map[string]map[string]*cachedFile),
+	}
+}
+
+func (cache *fileCache) get(uuid, path string) (*cachedFile, bool) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	file, ok := cache.files[uuid][path]
+	if !ok {
+		return nil, false
+	}
+	copied := *file
+	return &copied, true
+}
+
+func (cache *fileCache) put(uuid, path string, file *cachedFile) {
+	cache.mutex.Lock()
+	defer cache.mutex.Unlock()
+	if _, ok := cache.files[uuid]; !ok {
+		cache.files[uuid] = make(map[string]*cachedFile)
+	}
+	cache.files[uuid][path] = file
+}
+
+// ReadRemoteFile returns the contents of the regular file at path on the connected host
+// with uuid. Files larger than the configured maximum file size are refused. Contents are
+// cached for the rest of the session: for fileCacheTTL they are returned without asking
+// the host anything, after that they are read again only if the file's size or
+// modification time changed.
+func (s *Session) ReadRemoteFile(uuid, path string) ([]byte, error) {
+	reader, readable := s.API.(models.FileReader)
+	carver, carvable := s.API.(models.FileCarver)
+	if !readable && !carvable {
+		return nil, fmt.Errorf("The configured API does not support reading files")
+	}
+
+	cached, ok := s.files.get(uuid, path)
+	if ok && time.Since(cached.checked) < fileCacheTTL {
+		return cached.contents, nil
+	}
+
+	ctx, cancel := s.QueryContext()
+	defer cancel()
+
+	// The stat is bookkeeping, like a directory listing, so it isn't recorded in the
+	// host's query history
+	queryName, err := s.ContextAPI().ScheduleQueryContext(ctx, uuid, fmt.Sprintf(fileStatTemplate, strings.ReplaceAll(path, "'", "''")))
+	if err != nil {
+		return nil, waitError(ctx, err)
+	}
+	results, _, err := s.waitForResults(ctx, queryName, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) != 1 {
+		return nil, fmt.Errorf("No such file: %s", path)
+	}
+	if fileType := results[0]["type"]; fileType != "regular" {
+		return nil, fmt.Errorf("%s is a %s, not a regular file", path, fileType)
+	}
+	size, err := strconv.ParseInt(results[0]["size"], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read the size of %s: %s", path, err)
+	}
+	if limit := s.Config.FileSizeLimit(); size > limit {
+		return nil, fmt.Errorf("%s is %d bytes, larger than the %d byte limit set by maxFileSize", path, size, limit)
+	}
+
+	file := &cachedFile{size: results[0]["size"], mtime: results[0]["mtime"], checked: time.Now()}
+	if ok && cached.size == file.size && cached.mtime == file.mtime {
+		file.contents = cached.contents
+		s.files.put(uuid, path, file)
+		return file.contents, nil
+	}
+
+	if readable {
+		file.contents, err = reader.ReadFile(ctx, uuid, path)
+	} else {
+		file.contents, err = carve.ReadFile(ctx, carver, uuid, path)
+	}
+	if err != nil {
+		return nil, waitError(ctx, err)
+	}
+	s.files.put(uuid, path, file)
+	return file.contents, nil
+}
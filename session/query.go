@@ -0,0 +1,194 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/utils"
+)
+
+// ContextAPI returns the session's API as a models.GoQueryAPIContext
+func (s *Session) ContextAPI() models.GoQueryAPIContext {
+	return models.WithContext(s.API)
+}
+
+// QueryContext returns a context for waiting on a single query, bounded by the configured
+// query deadline and cancelled by ctrl C. The cancel function must always be called.
+func (s *Session) QueryContext() (context.Context, context.CancelFunc) {
+	ctx, cancelTimeout := context.WithTimeout(context.Background(), s.Config.QueryDeadline())
+	ctx, cancelInterrupt := utils.InterruptContext(ctx)
+	return ctx, func() {
+		cancelInterrupt()
+		cancelTimeout()
+	}
+}
+
+// ScheduleQuery schedules a query on a connected host through the session's API
+// and records it in that host's query history
+func (s *Session) ScheduleQuery(ctx context.Context, uuid, query string) (string, error) {
+	queryName, err := s.ContextAPI().ScheduleQueryContext(ctx, uuid, query)
+	if err != nil {
+		return "", err
+	}
+	if err := s.Hosts.AddQueryToHost(uuid, hosts.Query{Name: queryName, SQL: query}); err != nil {
+		return queryName, err
+	}
+	if s.Store != nil {
+		if err := s.Store.Add(uuid, queryName, query); err != nil {
+			fmt.Fprintf(s.Out, "%s\n", err)
+		}
+	}
+	return queryName, nil
+}
+
+// recordResults saves the outcome of a query to the session's Store, rowCount is the
+// total number of rows if results doesn't hold all of them
+func (s *Session) recordResults(queryName string, status models.QueryStatus, rowCount int, results models.Rows) {
+	if s.Store == nil {
+		return
+	}
+	if err := s.Store.Finish(queryName, status, rowCount, results); err != nil {
+		fmt.Fprintf(s.Out, "%s\n", err)
+	}
+}
+
+// ScheduleQueryAndWait schedules the provided query with the session's API and blocks until
+// results arrive, the configured query deadline passes, or ctrl C is pressed
+func (s *Session) ScheduleQueryAndWait(uuid, query string) (models.Rows, error) {
+	ctx, cancel := s.QueryContext()
+	defer cancel()
+
+	results := make([]map[string]string, 0)
+	queryName, err := s.ScheduleQuery(ctx, uuid, query)
+	if err != nil {
+		return results, fmt.Errorf("ScheduleQueryAndWait call failed: %s", waitError(ctx, err))
+	}
+
+	results, _, err = s.waitForResults(ctx, queryName, func() { fmt.Fprintf(s.Out, ".") })
+	fmt.Fprintf(s.Out, "\n")
+	return results, err
+}
+
+// ScheduleQueryOnHosts schedules the same query on every provided host concurrently
+// and returns without waiting for any results. Results are in the same order as targets.
+func (s *Session) ScheduleQueryOnHosts(ctx context.Context, targets []hosts.Host, query string) []utils.HostResult {
+	results := make([]utils.HostResult, len(targets))
+	var wg sync.WaitGroup
+	for i, host := range targets {
+		wg.Add(1)
+		go func(i int, host hosts.Host) {
+			defer wg.Done()
+			results[i].Host = host
+			results[i].QueryName, results[i].Err = s.ScheduleQuery(ctx, host.UUID, query)
+			if results[i].Err == nil {
+				results[i].Status = models.QueryStatus{State: models.QueryPending}
+			}
+		}(i, host)
+	}
+	wg.Wait()
+	return results
+}
+
+// ScheduleQueryOnHostsAndWait schedules the same query on every provided host and waits for
+// all of them to finish. A ctrl C interrupt cancels the wait on every host at once. Hosts
+// that fail or time out are reported in their HostResult instead of aborting the others.
+func (s *Session) ScheduleQueryOnHostsAndWait(targets []hosts.Host, query string) []utils.HostResult {
+	// Cancelling ctx stops every host's polling loop and in flight request
+	ctx, cancel := s.QueryContext()
+	defer cancel()
+
+	results := s.ScheduleQueryOnHosts(ctx, targets, query)
+	s.waitForAll(ctx, results)
+	return results
+}
+
+// ScheduleQueriesAndWait schedules several queries on the connected host with uuid at once
+// and waits for all of them to finish, so a failing query doesn't stop the others. Results
+// are in the same order as queries.
+func (s *Session) ScheduleQueriesAndWait(uuid string, queries []string) []utils.HostResult {
+	ctx, cancel := s.QueryContext()
+	defer cancel()
+
+	host, err := s.Hosts.GetHost(uuid)
+	results := make([]utils.HostResult, len(queries))
+	for i, query := range queries {
+		results[i].Host = host
+		if err != nil {
+			results[i].Err = err
+			continue
+		}
+		results[i].QueryName, results[i].Err = s.ScheduleQuery(ctx, uuid, query)
+	}
+	s.waitForAll(ctx, results)
+	return results
+}
+
+// waitForAll waits for every scheduled query in results concurrently, filling in its rows,
+// status and any error
+func (s *Session) waitForAll(ctx context.Context, results []utils.HostResult) {
+	var wg sync.WaitGroup
+	for i := range results {
+		if results[i].Err != nil {
+			results[i].Err = waitError(ctx, results[i].Err)
+			continue
+		}
+		wg.Add(1)
+		go func(result *utils.HostResult) {
+			defer wg.Done()
+			result.Rows, result.Status, result.Err = s.waitForResults(ctx, result.QueryName, nil)
+		}(&results[i])
+	}
+	wg.Wait()
+}
+
+// waitForResults polls for the results of queryName until they are no longer pending
+// or ctx is done. tick is called, if set, after every poll that is still pending.
+// A query that failed or expired on the host is returned as an error.
+func (s *Session) waitForResults(ctx context.Context, queryName string, tick func()) (models.Rows, models.QueryStatus, error) {
+	api := s.ContextAPI()
+	results, status, err := waitFor(ctx, func() (models.Rows, models.QueryStatus, error) {
+		return api.FetchResultsContext(ctx, queryName)
+	}, tick)
+	if status.State == models.QueryComplete || status.Err() != nil {
+		s.recordResults(queryName, status, len(results), results)
+	}
+	return results, status, err
+}
+
+// waitFor calls fetch until the query it reads is no longer pending or ctx is done.
+// tick is called, if set, after every poll that is still pending. A query that failed
+// or expired on the host is returned as an error.
+func waitFor(ctx context.Context, fetch func() (models.Rows, models.QueryStatus, error), tick func()) (models.Rows, models.QueryStatus, error) {
+	for {
+		results, status, err := fetch()
+		if err != nil {
+			return results, status, waitError(ctx, err)
+		}
+		if !status.Pending() {
+			return results, status, status.Err()
+		}
+		if tick != nil {
+			tick()
+		}
+		select {
+		case <-ctx.Done():
+			return results, status, waitError(ctx, ctx.Err())
+		case <-time.After(time.Second):
+		}
+	}
+}
+
+// waitError explains why waiting stopped if ctx ended it, otherwise err is returned as is
+func waitError(ctx context.Context, err error) error {
+	switch ctx.Err() {
+	case context.Canceled:
+		return fmt.Errorf("Waiting Cancelled")
+	case context.DeadlineExceeded:
+		return fmt.Errorf("Timed out waiting for results")
+	}
+	return err
+}
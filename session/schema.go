@@ -0,0 +1,118 @@
+package session
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+)
+
+const tableSchemaTemplate = "pragma table_info(%s)"
+
+// schemaRetryInterval is how long to wait before fetching a table's schema again
+// after a background fetch failed
+const schemaRetryInterval = time.Minute
+
+// schemaFetches tracks background schema lookups by host UUID and table. Tables are
+// removed once their columns are stored on the host, so an entry is either in flight
+// or a recent failure.
+type schemaFetches struct {
+	mutex   sync.Mutex
+	started map[string]time.Time
+}
+
+func newSchemaFetches() *schemaFetches {
+	return &schemaFetches{
+		started: make(map[string]time.Time),
+	}
+}
+
+// TableColumns returns the columns of table on the connected host with uuid, querying
+// the host and storing them on it the first time the table is looked up
+func (s *Session) TableColumns(uuid, table string) ([]hosts.Column, error) {
+	host, err := s.Hosts.GetHost(uuid)
+	if err != nil {
+		return nil, err
+	}
+	if columns, ok := host.Columns[table]; ok {
+		return columns, nil
+	}
+
+	ctx, cancel := s.QueryContext()
+	defer cancel()
+	return s.fetchTableColumns(ctx, uuid, table)
+}
+
+// CachedTableColumns returns the columns of table on the host with uuid for use while
+// completing queries. Unknown tables are looked up in the background so this never
+// blocks on the backend, and ok is false until their columns arrive.
+func (s *Session) CachedTableColumns(uuid, table string) ([]hosts.Column, bool) {
+	host, err := s.Hosts.GetHost(uuid)
+	if err != nil {
+		return nil, false
+	}
+	if columns, ok := host.Columns[table]; ok {
+		return columns, true
+	}
+
+	fetches := s.schemaFetches
+	key := uuid + "/" + table
+	fetches.mutex.Lock()
+	defer fetches.mutex.Unlock()
+	if started, ok := fetches.started[key]; ok && time.Since(started) < schemaRetryInterval {
+		return nil, false
+	}
+	fetches.started[key] = time.Now()
+
+	go func() {
+		ctx, cancel := context.WithTimeout(context.Background(), s.Config.QueryDeadline())
+		defer cancel()
+		if _, err := s.fetchTableColumns(ctx, uuid, table); err != nil {
+			return
+		}
+		fetches.mutex.Lock()
+		delete(fetches.started, key)
+		fetches.mutex.Unlock()
+	}()
+	return nil, false
+}
+
+// fetchTableColumns asks the host for the columns of table and stores them on the host.
+// The query is not recorded in the host's query history.
+func (s *Session) fetchTableColumns(ctx context.Context, uuid, table string) ([]hosts.Column, error) {
+	if !isTableName(table) {
+		return nil, fmt.Errorf("Invalid table name: %s", table)
+	}
+	queryName, err := s.ContextAPI().ScheduleQueryContext(ctx, uuid, fmt.Sprintf(tableSchemaTemplate, table))
+	if err != nil {
+		return nil, waitError(ctx, err)
+	}
+	results, _, err := s.waitForResults(ctx, queryName, nil)
+	if err != nil {
+		return nil, err
+	}
+	if len(results) == 0 {
+		return nil, fmt.Errorf("No such table: %s", table)
+	}
+
+	columns := make([]hosts.Column, 0, len(results))
+	for _, row := range results {
+		columns = append(columns, hosts.Column{Name: row["name"], Type: row["type"]})
+	}
+	return columns, s.Hosts.SetHostTableColumns(uuid, table, columns)
+}
+
+// isTableName guards the pragma against anything but a plain table name
+func isTableName(table string) bool {
+	if len(table) == 0 {
+		return false
+	}
+	for _, char := range table {
+		if !(char == '_' || char >= 'a' && char <= 'z' || char >= 'A' && char <= 'Z' || char >= '0' && char <= '9') {
+			return false
+		}
+	}
+	return true
+}
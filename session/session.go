@@ -0,0 +1,335 @@
+// Package session holds the state of a single goquery shell: the backend API,
+// configuration, connected hosts, command table and where output is written.
+// Several sessions can live in one process without sharing any state.
+package session
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/config"
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/jobs"
+	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/store"
+	"github.com/AbGuthrie/goquery/v2/utils"
+
+	prompt "github.com/c-bata/go-prompt"
+)
+
+// Command defines the functions required to add a new command to a goquery session
+type Command struct {
+	Execute     func(*Session, string) error
+	Help        func() string
+	Suggestions func(*Session, string) []prompt.Suggest
+}
+
+// ErrExit is returned by a command to end the session it runs in. Other sessions in the
+// process are unaffected.
+var ErrExit = errors.New("Exit requested")
+
+// Session is an independent goquery shell
+type Session struct {
+	API      models.GoQueryAPI
+	Config   *config.Config
+	Hosts    *hosts.Registry
+	Jobs     *jobs.Manager
+	Commands map[string]Command
+	Out      io.Writer
+
+	// Store keeps every query, and optionally its results, across sessions. It is nil if
+	// disabled by the config or if it couldn't be opened.
+	Store *store.Store
+
+	// LastResults is the most recent result set printed by a query command
+	LastResults models.Rows
+
+	// interactive is set while the REPL is running so output can be paged
+	interactive bool
+
+	directories   *directoryCache
+	schemaFetches *schemaFetches
+	files         *fileCache
+
+	// notices are messages from background goroutines waiting to be printed at the
+	// prompt, writing them straight to Out would draw over whatever is being typed
+	noticesMutex sync.Mutex
+	notices      []string
+}
+
+// New creates a session that talks to api, runs the provided command table and
+// writes everything meant for the user to out. Queries are only recorded on disk if
+// _config turns on QueryStore.
+func New(api models.GoQueryAPI, _config config.Config, commands map[string]Command, out io.Writer) *Session {
+	// Print errors/warnings with provided aliases, and print state of debug flags
+	_config.Validate()
+
+	s := &Session{
+		API:      api,
+		Config:   &_config,
+		Hosts:    hosts.NewRegistry(),
+		Commands: commands,
+		Out:      out,
+
+		directories:   newDirectoryCache(),
+		schemaFetches: newSchemaFetches(),
+		files:         newFileCache(),
+	}
+	s.Jobs = jobs.NewManager(api, _config.QueryDeadline(), s.notifyJobFinished)
+
+	if !s.Config.QueryStore {
+		return s
+	}
+	storeDir := s.Config.QueryHistoryDir
+	var err error
+	if storeDir == "" {
+		storeDir, err = store.DefaultDir()
+	}
+	if err == nil {
+		s.Store, err = store.Open(storeDir, s.Config.HistoryResultLimit())
+	}
+	if err != nil {
+		fmt.Fprintf(out, "Query history will not be saved: %s\n", err)
+	}
+	return s
+}
+
+// notifyJobFinished records a finished background job and queues a one line notice
+// about it unless something is already waiting on it
+func (s *Session) notifyJobFinished(job jobs.Job, waited bool) {
+	if job.Status != jobs.StatusCancelled {
+		status := models.QueryStatus{State: models.QueryState(job.Status)}
+		if job.Err != nil {
+			status.Message = job.Err.Error()
+		}
+		s.recordResults(job.Name, status, len(job.Rows), job.Rows)
+	}
+	if waited {
+		return
+	}
+	s.queueNotice(fmt.Sprintf("[job %s] %s on %s after %s, view with .resume %s",
+		job.Status, job.Name, job.Host.ComputerName, job.Duration().Round(time.Second), job.Name))
+}
+
+func (s *Session) queueNotice(notice string) {
+	s.noticesMutex.Lock()
+	defer s.noticesMutex.Unlock()
+	s.notices = append(s.notices, notice)
+}
+
+// printNotices writes out every queued notice. It is called before the prompt is drawn
+// and between script commands, never while a command is producing output.
+func (s *Session) printNotices() {
+	s.noticesMutex.Lock()
+	notices := s.notices
+	s.notices = nil
+	s.noticesMutex.Unlock()
+	for _, notice := range notices {
+		fmt.Fprintf(s.Out, "%s\n", notice)
+	}
+}
+
+// Run starts the interactive prompt REPL for the session. It returns once .exit is run
+// or Ctrl-D is pressed on an empty line.
+func (s *Session) Run() {
+	history, err := utils.LoadHistoryFile()
+	if err != nil {
+		fmt.Fprintf(s.Out, "Unable to load history file %s\n", err)
+	}
+
+	// Input returns an empty line for both Enter and Ctrl-D, so note when Enter was pressed
+	submitted := false
+	submit := func(*prompt.Buffer) { submitted = true }
+	p := prompt.New(
+		func(string) {},
+		s.completer,
+		prompt.OptionPrefix("goquery> "),
+		prompt.OptionLivePrefix(s.refreshLivePrefix),
+		prompt.OptionTitle("goquery"),
+		prompt.OptionHistory(history),
+		prompt.OptionAddKeyBind(
+			prompt.KeyBind{Key: prompt.Enter, Fn: submit},
+			prompt.KeyBind{Key: prompt.ControlJ, Fn: submit},
+			prompt.KeyBind{Key: prompt.ControlM, Fn: submit},
+		),
+	)
+	s.interactive = true
+	defer func() { s.interactive = false }()
+	for {
+		s.printNotices()
+		submitted = false
+		input := p.Input()
+		if !submitted {
+			return
+		}
+		if s.executor(input) {
+			return
+		}
+	}
+}
+
+// RunScript runs every line read from script through the same commands and aliases
+// as the REPL. Blank lines and lines starting with # are skipped. Execution stops at the
+// first failing command unless continueOnError is set, or at .exit, and an error is
+// returned if any command failed.
+func (s *Session) RunScript(script io.Reader, continueOnError bool) error {
+	failures := 0
+	scanner := bufio.NewScanner(script)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if len(line) == 0 || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fmt.Fprintf(s.Out, "goquery> %s\n", line)
+		err := s.Execute(line)
+		s.printNotices()
+		if errors.Is(err, ErrExit) {
+			break
+		}
+		if err != nil {
+			fmt.Fprintf(s.Out, "%s\n", err)
+			failures++
+			if !continueOnError {
+				return fmt.Errorf("Script stopped after a failed command")
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("Could not read script: %s", err)
+	}
+	if failures > 0 {
+		return fmt.Errorf("%d command(s) failed", failures)
+	}
+	return nil
+}
+
+// Execute runs a single line of input as a command or alias and returns any failure
+func (s *Session) Execute(input string) error {
+	// Separate command and arguments
+	input = strings.TrimSpace(input)
+	args := strings.Split(input, " ")
+	if len(args) == 0 {
+		return nil
+	}
+
+	// Lookup and run command in command map
+	if command, ok := s.Commands[args[0]]; ok {
+		err := command.Execute(s, input)
+		if errors.Is(err, ErrExit) {
+			return err
+		}
+		if err != nil {
+			return fmt.Errorf("%s: %w", args[0], err)
+		}
+		return nil
+	}
+
+	// Command not found, was this command aliased?
+	alias, found := s.Config.Aliases[args[0]]
+	if !found {
+		return fmt.Errorf("No such command: %s", args[0])
+	}
+	realizedCommand, err := utils.InterpolateArguments(input, alias.Command)
+	if err != nil {
+		return fmt.Errorf("Alias error: %s", err)
+	}
+
+	// Run the parsed and interpolated alias through Execute again
+	return s.Execute(realizedCommand)
+}
+
+// PrintRows writes rows to the session output in the current print mode
+func (s *Session) PrintRows(rows models.Rows) {
+	if err := utils.FprintQueryResults(s.Out, rows, s.Config.PrintMode); err != nil {
+		fmt.Fprintf(s.Out, "%s\n", err)
+	}
+}
+
+// PrintResults prints a query's results and remembers them as the session's LastResults.
+// Output taller than the terminal is paged in an interactive session.
+func (s *Session) PrintResults(results models.Rows) {
+	s.LastResults = results
+	err := utils.FprintQueryResults(s.PagedOut(), results, s.Config.PrintMode)
+	if err != nil && !errors.Is(err, utils.ErrPagerQuit) {
+		fmt.Fprintf(s.Out, "%s\n", err)
+	}
+}
+
+func (s *Session) refreshLivePrefix() (string, bool) {
+	// Prototype for showing current connected host state in
+	// input line prefix
+	subPrefix := ""
+	currentHost, err := s.Hosts.GetCurrentHost()
+	if err == nil {
+		subPrefix = " | " + currentHost.ComputerName + ":" + currentHost.CurrentDirectory
+	}
+	return fmt.Sprintf("goquery%s> ", subPrefix), true
+}
+
+// executor runs a line typed at the prompt and reports whether the session should end
+func (s *Session) executor(input string) bool {
+	defer func() {
+		// Write history entry
+		if err := utils.UpdateHistoryFile(input); err != nil {
+			fmt.Fprintf(s.Out, "Failed to write history file: %s\n", err)
+		}
+	}()
+
+	err := s.Execute(input)
+	if errors.Is(err, ErrExit) {
+		return true
+	}
+	if err != nil {
+		fmt.Fprintf(s.Out, "%s\n", err)
+	}
+	return false
+}
+
+func (s *Session) completer(in prompt.Document) []prompt.Suggest {
+	command := strings.Split(in.CurrentLine(), " ")[0]
+	// Nothing has been typed at the prompt
+	if command == "" {
+		return []prompt.Suggest{}
+	}
+
+	// Suggest any top level command
+	if _, ok := s.Commands[command]; !ok {
+		prompts := []prompt.Suggest{}
+		// We also need to sort the final array because go traverses maps non
+		// deterministically
+		suggestions := make([]string, 0)
+
+		// Add all command suggestions
+		for name := range s.Commands {
+			suggestions = append(suggestions, name)
+		}
+		// Add all alias suggestions
+		for name := range s.Config.Aliases {
+			suggestions = append(suggestions, name)
+		}
+
+		sort.Strings(suggestions)
+		for _, suggestion := range suggestions {
+			if alias, ok := s.Config.Aliases[suggestion]; ok {
+				description := alias.Description
+				if len(description) == 0 {
+					description = alias.Command
+				}
+				prompts = append(prompts, prompt.Suggest{Text: suggestion, Description: description})
+			} else if command, ok := s.Commands[suggestion]; ok {
+				prompts = append(prompts, prompt.Suggest{Text: suggestion, Description: command.Help()})
+			}
+		}
+		return prompt.FilterHasPrefix(prompts, command, true)
+	}
+
+	// Call into the command to ask for further suggestions
+	commandStruct := s.Commands[command]
+	return prompt.FilterHasPrefix(commandStruct.Suggestions(s, in.CurrentLine()), in.GetWordBeforeCursor(), true)
+}
@@ -0,0 +1,193 @@
+package session
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/config"
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/internal/apitest"
+	"github.com/AbGuthrie/goquery/v2/jobs"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// newTestSession returns a session with host "h" connected and current. Nothing is stored
+// on disk unless _config sets queryHistoryDir.
+func newTestSession(t *testing.T, api models.GoQueryAPI, _config config.Config) (*Session, *bytes.Buffer) {
+	_config.QueryStore = _config.QueryHistoryDir != ""
+	out := &bytes.Buffer{}
+	s := New(api, _config, map[string]Command{}, out)
+	host, err := api.CheckHost("h")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Hosts.Register(host); err != nil {
+		t.Fatal(err)
+	}
+	return s, out
+}
+
+func TestNewWithoutQueryStore(t *testing.T) {
+	// A zero config must not write to the home directory
+	s := New(apitest.New(nil), config.Config{}, map[string]Command{}, &bytes.Buffer{})
+	if s.Store != nil {
+		t.Fatal("the query store should be off unless QueryStore is set")
+	}
+}
+
+func TestQueryStoreKeepsResultsOnlyWhenConfigured(t *testing.T) {
+	api := apitest.New(map[string]models.Rows{"select 1": {{"1": "1"}}})
+	for _, resultSize := range []int64{0, 1024} {
+		dir, err := ioutil.TempDir("", "goquery-session")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		s, _ := newTestSession(t, api, config.Config{QueryHistoryDir: dir, HistoryResultSize: resultSize})
+		if s.Store == nil {
+			t.Fatal("expected a Store in queryHistoryDir")
+		}
+		if _, err := s.ScheduleQueryAndWait("h", "select 1"); err != nil {
+			t.Fatal(err)
+		}
+		history, err := s.Store.History("h")
+		if err != nil || len(history) != 1 || history[0].Status != models.QueryComplete {
+			t.Fatalf("History() = %+v, %v", history, err)
+		}
+		_, record, _, _ := s.Store.Find(history[0].Name)
+		if stored := record.Results != nil; stored != (resultSize > 0) {
+			t.Fatalf("historyResultSize %d: results stored = %v", resultSize, stored)
+		}
+	}
+}
+
+func TestJobNoticesWaitForThePrompt(t *testing.T) {
+	s, out := newTestSession(t, apitest.New(nil), config.Config{})
+	job := jobs.Job{Name: "q", Host: hosts.Host{ComputerName: "host-h"}, Status: jobs.StatusComplete}
+	s.notifyJobFinished(job, false)
+	if out.Len() != 0 {
+		t.Fatalf("a finished job wrote to the output straight away: %q", out)
+	}
+	s.printNotices()
+	if !strings.Contains(out.String(), "[job Complete] q on host-h") {
+		t.Fatalf("expected the queued notice, got %q", out)
+	}
+	out.Reset()
+	s.printNotices()
+	s.notifyJobFinished(job, true)
+	s.printNotices()
+	if out.Len() != 0 {
+		t.Fatalf("notices should print once and not at all for waited on jobs, got %q", out)
+	}
+}
+
+// pagedStubAPI is apitest.API read a page at a time through models.GoQueryAPIPager
+type pagedStubAPI struct {
+	*apitest.API
+}
+
+func (api pagedStubAPI) FetchResultsPage(ctx context.Context, queryName string, offset, limit int) (models.Rows, models.QueryStatus, error) {
+	rows, status, err := api.FetchResults(queryName)
+	if offset > len(rows) {
+		offset = len(rows)
+	}
+	if offset+limit < len(rows) {
+		rows = rows[:offset+limit]
+	}
+	return rows[offset:], status, err
+}
+
+func TestScheduleQueryAndPrintKeepsEveryPage(t *testing.T) {
+	rows := make(models.Rows, PageSize+500)
+	for i := range rows {
+		rows[i] = map[string]string{"pid": fmt.Sprint(i)}
+	}
+	dir, err := ioutil.TempDir("", "goquery-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	api := pagedStubAPI{apitest.New(map[string]models.Rows{"select pid from processes": rows})}
+	s, _ := newTestSession(t, api, config.Config{QueryHistoryDir: dir, HistoryResultSize: 1 << 20, PrintMode: config.PrintCSV})
+	if err := s.ScheduleQueryAndPrint("h", "select pid from processes"); err != nil {
+		t.Fatal(err)
+	}
+	if len(s.LastResults) != len(rows) || s.LastResults[len(rows)-1]["pid"] != fmt.Sprint(len(rows)-1) {
+		t.Fatalf("LastResults has %d rows, want %d", len(s.LastResults), len(rows))
+	}
+	history, _ := s.Store.History("h")
+	_, record, _, _ := s.Store.Find(history[0].Name)
+	if record.RowCount != len(rows) || len(record.Results) != len(rows) {
+		t.Fatalf("stored %d of %d rows, want %d", len(record.Results), record.RowCount, len(rows))
+	}
+}
+
+// readerStubAPI is apitest.API that also implements models.FileReader
+type readerStubAPI struct {
+	*apitest.API
+	reads int
+}
+
+func (api *readerStubAPI) ReadFile(ctx context.Context, uuid string, path string) ([]byte, error) {
+	api.reads++
+	return []byte("contents of " + path), nil
+}
+
+func TestReadRemoteFileCachesWithoutRecording(t *testing.T) {
+	stat := fmt.Sprintf(fileStatTemplate, "/etc/hosts")
+	api := &readerStubAPI{API: apitest.New(map[string]models.Rows{
+		stat: {{"size": "10", "type": "regular", "mtime": "1"}},
+	})}
+	dir, err := ioutil.TempDir("", "goquery-session")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s, _ := newTestSession(t, api, config.Config{QueryHistoryDir: dir})
+
+	for i := 0; i < 2; i++ {
+		contents, err := s.ReadRemoteFile("h", "/etc/hosts")
+		if err != nil || string(contents) != "contents of /etc/hosts" {
+			t.Fatalf("ReadRemoteFile() = %q, %v", contents, err)
+		}
+	}
+	if len(api.Scheduled()) != 1 || api.reads != 1 {
+		t.Fatalf("a cached read scheduled %d stat(s) and read %d time(s), want 1 each", len(api.Scheduled()), api.reads)
+	}
+
+	// Once the cache is stale the file is checked again, but only read if it changed
+	s.files.files["h"]["/etc/hosts"].checked = time.Now().Add(-fileCacheTTL)
+	s.ReadRemoteFile("h", "/etc/hosts")
+	if len(api.Scheduled()) != 2 || api.reads != 1 {
+		t.Fatalf("an unchanged file scheduled %d stat(s) and read %d time(s), want 2 and 1", len(api.Scheduled()), api.reads)
+	}
+	s.files.files["h"]["/etc/hosts"].checked = time.Now().Add(-fileCacheTTL)
+	api.SetResults(stat, models.Rows{{"size": "10", "type": "regular", "mtime": "2"}})
+	s.ReadRemoteFile("h", "/etc/hosts")
+	if api.reads != 2 {
+		t.Fatalf("a modified file was not read again")
+	}
+
+	host, _ := s.Hosts.GetHost("h")
+	history, _ := s.Store.History("h")
+	if len(host.QueryHistory) != 0 || len(history) != 0 {
+		t.Fatalf("stat queries were recorded: %v, %v", host.QueryHistory, history)
+	}
+}
+
+func TestScheduleQueryAndWaitReportsFailedQueries(t *testing.T) {
+	s, _ := newTestSession(t, apitest.New(nil), config.Config{})
+	_, err := s.ScheduleQueryAndWait("h", "select * from missing")
+	if !errors.Is(err, models.ErrQueryFailed) || !strings.Contains(err.Error(), "no such table") {
+		t.Fatalf("ScheduleQueryAndWait() of a failing query = %v, want ErrQueryFailed with the message", err)
+	}
+}
@@ -0,0 +1,124 @@
+package session
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+	"github.com/AbGuthrie/goquery/v2/utils"
+)
+
+// PageSize is how many rows are fetched at a time from APIs that implement models.GoQueryAPIPager
+const PageSize = 1000
+
+// maxRetainedRows caps how many streamed rows are kept as LastResults for .export and
+// in the query store
+const maxRetainedRows = 100000
+
+// ScheduleQueryAndStream schedules the provided query and passes its results to handle as
+// they are fetched. APIs that implement models.GoQueryAPIPager are read a page at a time,
+// otherwise handle is called once with every row. An error returned by handle stops
+// fetching and is returned as is.
+func (s *Session) ScheduleQueryAndStream(uuid, query string, handle func(models.Rows) error) error {
+	_, err := s.streamQuery(uuid, query, handle)
+	return err
+}
+
+// streamQuery is ScheduleQueryAndStream that also returns the rows passed to handle so far,
+// or nil if there were more than maxRetainedRows of them. The same rows are recorded in
+// the query store so only one copy of a large result set is ever held.
+func (s *Session) streamQuery(uuid, query string, handle func(models.Rows) error) (models.Rows, error) {
+	ctx, cancel := s.QueryContext()
+	defer cancel()
+
+	queryName, err := s.ScheduleQuery(ctx, uuid, query)
+	if err != nil {
+		return nil, fmt.Errorf("ScheduleQueryAndWait call failed: %s", waitError(ctx, err))
+	}
+
+	tick := func() { fmt.Fprintf(s.Out, ".") }
+	pager, ok := s.API.(models.GoQueryAPIPager)
+	if !ok {
+		results, _, err := s.waitForResults(ctx, queryName, tick)
+		fmt.Fprintf(s.Out, "\n")
+		if err != nil {
+			return nil, err
+		}
+		if len(results) > maxRetainedRows {
+			return nil, handle(results)
+		}
+		return results, handle(results)
+	}
+
+	offset := 0
+	fetchPage := func() (models.Rows, models.QueryStatus, error) {
+		return pager.FetchResultsPage(ctx, queryName, offset, PageSize)
+	}
+	retained := make(models.Rows, 0)
+	results, status, err := waitFor(ctx, fetchPage, tick)
+	fmt.Fprintf(s.Out, "\n")
+	for err == nil {
+		if retained != nil {
+			retained = append(retained, results...)
+			if len(retained) > maxRetainedRows {
+				retained = nil
+			}
+		}
+		if err := handle(results); err != nil {
+			return retained, err
+		}
+		if len(results) < PageSize {
+			s.recordResults(queryName, status, offset+len(results), retained)
+			return retained, nil
+		}
+		offset += len(results)
+		results, status, err = fetchPage()
+		if err == nil {
+			err = status.Err()
+		}
+	}
+	if status.Err() != nil {
+		s.recordResults(queryName, status, 0, nil)
+	}
+	return nil, waitError(ctx, err)
+}
+
+// ScheduleQueryAndPrint schedules the provided query and prints its results as they are
+// fetched, paging output that is taller than the terminal in an interactive session.
+// The results are kept as LastResults unless there are too many of them.
+func (s *Session) ScheduleQueryAndPrint(uuid, query string) error {
+	printer := utils.NewRowPrinter(s.PagedOut(), s.Config.PrintMode)
+	retained, err := s.streamQuery(uuid, query, printer.Print)
+	if errors.Is(err, utils.ErrPagerQuit) {
+		s.LastResults = retained
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if err := printer.Close(); err != nil && !errors.Is(err, utils.ErrPagerQuit) {
+		return err
+	}
+
+	s.LastResults = retained
+	if retained == nil {
+		fmt.Fprintf(s.Out, "%d rows is too many to keep for .export, narrow the query to export it\n", printer.Rows())
+	}
+	return nil
+}
+
+// PagedOut wraps the session output in a pager when the session is interactive and
+// writing to a terminal, unless paging is disabled in the config
+func (s *Session) PagedOut() io.Writer {
+	if !s.interactive || s.Config.DisablePager || s.Out != io.Writer(os.Stdout) {
+		return s.Out
+	}
+	height, ok := utils.TerminalHeight()
+	if !ok {
+		return s.Out
+	}
+	// Leave a line for the pager's own prompt
+	return utils.NewPager(s.Out, os.Stdin, height-1)
+}
@@ -0,0 +1,302 @@
+// Package store keeps a record of every query goquery schedules, and optionally its
+// results, on disk so history survives restarts. Each host has its own append only
+// ndjson file, where a query is written once when it is scheduled and again when it
+// finishes, and later lines for a query update the earlier ones.
+package store
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"os/user"
+	"path"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+const recordFileExtension = ".ndjson"
+
+// Record is everything stored about a single query on a host
+type Record struct {
+	Name     string            `json:"name"`
+	SQL      string            `json:"sql,omitempty"`
+	Time     time.Time         `json:"time"`
+	Status   models.QueryState `json:"status"`
+	Message  string            `json:"message,omitempty"`
+	RowCount int               `json:"rowCount"`
+	// Results is nil unless the results were small enough to keep
+	Results models.Rows `json:"results"`
+}
+
+// recordLine is how a Record is written to disk, with its results already encoded
+type recordLine struct {
+	Record
+	Results json.RawMessage `json:"results"`
+}
+
+// Store reads and writes query records under a directory. It is safe for concurrent use.
+// Each host's records are read once and then kept up to date in memory, without their
+// results, so queries written by another goquery process later are not seen.
+type Store struct {
+	dir            string
+	maxResultBytes int64
+
+	mutex sync.Mutex
+	// hostOf maps queries scheduled through this Store that haven't finished yet to the
+	// host they run on
+	hostOf map[string]string
+	hosts  map[string]*hostRecords
+}
+
+// hostRecords are the records of one host folded into one per query
+type hostRecords struct {
+	records []Record
+	index   map[string]int
+}
+
+func (host *hostRecords) apply(record Record) {
+	i, ok := host.index[record.Name]
+	if !ok {
+		host.index[record.Name] = len(host.records)
+		host.records = append(host.records, record)
+		return
+	}
+	host.records[i].Status = record.Status
+	host.records[i].Message = record.Message
+	host.records[i].RowCount = record.RowCount
+	host.records[i].Results = record.Results
+}
+
+// DefaultDir is where query records are kept unless told otherwise, ~/.goquery/queries
+func DefaultDir() (string, error) {
+	usr, err := user.Current()
+	if err != nil {
+		return "", fmt.Errorf("Failed to fetch user info for home directory: %s", err)
+	}
+	return path.Join(usr.HomeDir, ".goquery", "queries"), nil
+}
+
+// Open creates a Store in dir, creating it if needed. Results larger than maxResultBytes
+// once encoded are not kept, only their row count, so no results are kept if it is zero.
+func Open(dir string, maxResultBytes int64) (*Store, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("Could not create query store: %s", err)
+	}
+	return &Store{
+		dir:            dir,
+		maxResultBytes: maxResultBytes,
+		hostOf:         make(map[string]string),
+		hosts:          make(map[string]*hostRecords),
+	}, nil
+}
+
+// Add records that the query name was scheduled on the host with uuid
+func (store *Store) Add(uuid, name, sql string) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	store.hostOf[name] = uuid
+	return store.append(uuid, Record{
+		Name:   name,
+		SQL:    sql,
+		Time:   time.Now(),
+		Status: models.QueryPending,
+	}, nil)
+}
+
+// Finish records the outcome of a query previously passed to Add. rowCount may be more
+// than len(results) if not every row was kept. Queries this Store didn't schedule, like
+// the internal lookups goquery makes, and queries already finished are ignored.
+func (store *Store) Finish(name string, status models.QueryStatus, rowCount int, results models.Rows) error {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	uuid, ok := store.hostOf[name]
+	if !ok {
+		return nil
+	}
+
+	record := Record{
+		Name:     name,
+		Time:     time.Now(),
+		Status:   status.State,
+		Message:  status.Message,
+		RowCount: rowCount,
+	}
+	var encoded json.RawMessage
+	if results != nil && len(results) == rowCount {
+		encoded = encodeResults(results, store.maxResultBytes)
+	}
+	if err := store.append(uuid, record, encoded); err != nil {
+		return err
+	}
+	delete(store.hostOf, name)
+	return nil
+}
+
+// encodeResults returns results encoded as JSON, or nil as soon as they grow past
+// maxBytes so large result sets are never encoded in full
+func encodeResults(results models.Rows, maxBytes int64) json.RawMessage {
+	var encoded bytes.Buffer
+	encoded.WriteByte('[')
+	for i, row := range results {
+		if i > 0 {
+			encoded.WriteByte(',')
+		}
+		rowBytes, err := json.Marshal(row)
+		if err != nil {
+			return nil
+		}
+		encoded.Write(rowBytes)
+		// Leave room for the closing bracket
+		if int64(encoded.Len())+1 > maxBytes {
+			return nil
+		}
+	}
+	encoded.WriteByte(']')
+	if int64(encoded.Len()) > maxBytes {
+		return nil
+	}
+	return encoded.Bytes()
+}
+
+// History returns every query recorded for the host with uuid, oldest first. Results
+// are not loaded, use Find to get them.
+func (store *Store) History(uuid string) ([]Record, error) {
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	host, err := store.load(uuid)
+	if err != nil {
+		return nil, err
+	}
+	return append([]Record{}, host.records...), nil
+}
+
+// Find looks up a query by name on any host, returning the host's uuid and its record
+// including any stored results
+func (store *Store) Find(name string) (string, Record, bool, error) {
+	uuids, err := store.Hosts()
+	if err != nil {
+		return "", Record{}, false, err
+	}
+
+	store.mutex.Lock()
+	defer store.mutex.Unlock()
+	// Check the host it runs on first if it is still running in this session
+	if uuid, ok := store.hostOf[name]; ok {
+		uuids = append([]string{uuid}, uuids...)
+	}
+	for _, uuid := range uuids {
+		host, err := store.load(uuid)
+		if err != nil {
+			return "", Record{}, false, err
+		}
+		if _, ok := host.index[name]; !ok {
+			continue
+		}
+		withResults, err := store.read(uuid, name)
+		if err != nil {
+			return "", Record{}, false, err
+		}
+		return uuid, withResults.records[0], true, nil
+	}
+	return "", Record{}, false, nil
+}
+
+// Hosts returns the uuid of every host with recorded queries
+func (store *Store) Hosts() ([]string, error) {
+	files, err := ioutil.ReadDir(store.dir)
+	if err != nil {
+		return nil, fmt.Errorf("Could not read query store: %s", err)
+	}
+	uuids := make([]string, 0, len(files))
+	for _, file := range files {
+		if !strings.HasSuffix(file.Name(), recordFileExtension) {
+			continue
+		}
+		uuid, err := url.PathUnescape(strings.TrimSuffix(file.Name(), recordFileExtension))
+		if err != nil {
+			continue
+		}
+		uuids = append(uuids, uuid)
+	}
+	return uuids, nil
+}
+
+func (store *Store) hostPath(uuid string) string {
+	// Not every API uses UUIDs, escape anything that can't be in a file name
+	return path.Join(store.dir, url.PathEscape(uuid)+recordFileExtension)
+}
+
+// append writes record, with results if they are set, and updates the cached records
+func (store *Store) append(uuid string, record Record, results json.RawMessage) error {
+	line, err := json.Marshal(recordLine{Record: record, Results: results})
+	if err != nil {
+		return fmt.Errorf("Could not encode query record: %s", err)
+	}
+	file, err := os.OpenFile(store.hostPath(uuid), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("Could not open query store: %s", err)
+	}
+	defer file.Close()
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("Could not write query store: %s", err)
+	}
+
+	if host, ok := store.hosts[uuid]; ok {
+		host.apply(record)
+	}
+	return nil
+}
+
+// load returns the records of a host without their results, reading them the first time
+func (store *Store) load(uuid string) (*hostRecords, error) {
+	if host, ok := store.hosts[uuid]; ok {
+		return host, nil
+	}
+	host, err := store.read(uuid, "")
+	if err != nil {
+		return nil, err
+	}
+	for i := range host.records {
+		host.records[i].Results = nil
+	}
+	store.hosts[uuid] = host
+	return host, nil
+}
+
+// read folds the lines of a host's file into one record per query, only reading the
+// query called name if it is set
+func (store *Store) read(uuid, name string) (*hostRecords, error) {
+	host := &hostRecords{
+		records: make([]Record, 0),
+		index:   make(map[string]int),
+	}
+	file, err := os.Open(store.hostPath(uuid))
+	if os.IsNotExist(err) {
+		return host, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("Could not open query store: %s", err)
+	}
+	defer file.Close()
+
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		var record Record
+		// A line cut short by a crash is skipped rather than losing the whole file
+		if len(line) > 0 && json.Unmarshal(line, &record) == nil && (name == "" || record.Name == name) {
+			host.apply(record)
+		}
+		if err != nil {
+			break
+		}
+	}
+	return host, nil
+}
@@ -0,0 +1,155 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func tempStore(t *testing.T, maxResultBytes int64) (*Store, string) {
+	dir, err := ioutil.TempDir("", "goquery-store")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store, err := Open(dir, maxResultBytes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return store, dir
+}
+
+func TestStoreKeepsResultsWithinLimit(t *testing.T) {
+	store, dir := tempStore(t, 1024)
+	defer os.RemoveAll(dir)
+	results := models.Rows{{"a": "1"}, {"a": "2"}}
+	if err := store.Add("host/1", "q1", "select a"); err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Finish("q1", models.QueryStatus{State: models.QueryComplete}, len(results), results); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new Store reads what the first one wrote
+	reopened, err := Open(dir, 1024)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uuids, err := reopened.Hosts()
+	if err != nil || !reflect.DeepEqual(uuids, []string{"host/1"}) {
+		t.Fatalf("Hosts() = %v, %v", uuids, err)
+	}
+	history, err := reopened.History("host/1")
+	if err != nil || len(history) != 1 {
+		t.Fatalf("History() = %v, %v", history, err)
+	}
+	if history[0].Status != models.QueryComplete || history[0].RowCount != 2 || history[0].SQL != "select a" || history[0].Results != nil {
+		t.Fatalf("unexpected history record: %+v", history[0])
+	}
+	uuid, record, ok, err := reopened.Find("q1")
+	if err != nil || !ok || uuid != "host/1" {
+		t.Fatalf("Find() = %s, %v, %v", uuid, ok, err)
+	}
+	if !reflect.DeepEqual(record.Results, results) {
+		t.Fatalf("Find() results = %v, want %v", record.Results, results)
+	}
+}
+
+func TestStoreDropsResultsOverLimit(t *testing.T) {
+	for _, limit := range []int64{0, 10} {
+		store, dir := tempStore(t, limit)
+		defer os.RemoveAll(dir)
+		results := models.Rows{{"column": "a value longer than the limit"}}
+		store.Add("host", "q", "select 1")
+		if err := store.Finish("q", models.QueryStatus{State: models.QueryComplete}, 1, results); err != nil {
+			t.Fatal(err)
+		}
+		_, record, ok, err := store.Find("q")
+		if err != nil || !ok {
+			t.Fatalf("Find() = %v, %v", ok, err)
+		}
+		if record.Results != nil || record.RowCount != 1 {
+			t.Fatalf("limit %d: expected only the row count to be kept, got %+v", limit, record)
+		}
+	}
+}
+
+func TestStoreDropsPartialResults(t *testing.T) {
+	store, dir := tempStore(t, 1024)
+	defer os.RemoveAll(dir)
+	store.Add("host", "q", "select 1")
+	store.Finish("q", models.QueryStatus{State: models.QueryComplete}, 5, models.Rows{{"a": "1"}})
+	_, record, _, _ := store.Find("q")
+	if record.Results != nil || record.RowCount != 5 {
+		t.Fatalf("expected no results for a partial result set, got %+v", record)
+	}
+}
+
+func TestStoreIgnoresUnknownQueries(t *testing.T) {
+	store, dir := tempStore(t, 1024)
+	defer os.RemoveAll(dir)
+	if err := store.Finish("internal", models.QueryStatus{State: models.QueryComplete}, 0, models.Rows{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, ok, _ := store.Find("internal"); ok {
+		t.Fatal("a query that was never added should not be recorded")
+	}
+}
+
+func TestStoreForgetsFinishedQueries(t *testing.T) {
+	store, dir := tempStore(t, 1024)
+	defer os.RemoveAll(dir)
+	store.Add("host", "q", "select 1")
+	store.Finish("q", models.QueryStatus{State: models.QueryComplete}, 1, models.Rows{{"1": "1"}})
+	if len(store.hostOf) != 0 {
+		t.Fatalf("hostOf still holds finished queries: %v", store.hostOf)
+	}
+
+	// Finishing it again, as resuming a query does, doesn't record it twice
+	store.Finish("q", models.QueryStatus{State: models.QueryComplete}, 1, models.Rows{{"1": "1"}})
+	contents, err := ioutil.ReadFile(store.hostPath("host"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lines := strings.Count(string(contents), "\n"); lines != 2 {
+		t.Fatalf("expected 2 lines for q, found %d", lines)
+	}
+	if _, record, ok, err := store.Find("q"); err != nil || !ok || record.Status != models.QueryComplete {
+		t.Fatalf("Find() = %+v, %v, %v", record, ok, err)
+	}
+}
+
+func TestStoreSkipsTruncatedLines(t *testing.T) {
+	store, dir := tempStore(t, 1024)
+	defer os.RemoveAll(dir)
+	store.Add("host", "q", "select 1")
+	file, err := os.OpenFile(store.hostPath("host"), os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.WriteString(`{"name":"q","status":"Comp`)
+	file.Close()
+
+	reopened, _ := Open(dir, 1024)
+	history, err := reopened.History("host")
+	if err != nil || len(history) != 1 || history[0].Status != models.QueryPending {
+		t.Fatalf("History() = %+v, %v", history, err)
+	}
+}
+
+func TestEncodeResultsStopsAtLimit(t *testing.T) {
+	rows := models.Rows{{"a": "1"}, {"a": "2"}}
+	if encoded := encodeResults(rows, 100); string(encoded) != `[{"a":"1"},{"a":"2"}]` {
+		t.Fatalf("encodeResults() = %s", encoded)
+	}
+	// Exactly the encoded size fits, one byte less doesn't
+	if encodeResults(rows, 21) == nil || encodeResults(rows, 20) != nil {
+		t.Fatal("encodeResults() limit is off")
+	}
+	if encoded := encodeResults(models.Rows{}, 2); string(encoded) != "[]" {
+		t.Fatalf("encodeResults() of no rows = %s", encoded)
+	}
+}
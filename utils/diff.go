@@ -0,0 +1,102 @@
+package utils
+
+import (
+	"encoding/json"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// RowChange is a row whose key columns matched between two result sets while its other
+// columns didn't
+type RowChange struct {
+	Before map[string]string
+	After  map[string]string
+}
+
+// DiffRows compares two result sets and returns the rows only found in before, the rows
+// only found in after, and the rows found in both whose other columns changed. Rows are
+// matched on the key columns, or on every column if key is empty in which case nothing is
+// ever changed, and a row repeated more often on one side counts as a difference.
+func DiffRows(before, after models.Rows, key []string) (removed, added models.Rows, changed []RowChange) {
+	// Rows with the same key are paired up in order
+	unmatched := make(map[string][]int)
+	for i, row := range before {
+		identity := rowIdentity(row, key)
+		unmatched[identity] = append(unmatched[identity], i)
+	}
+	matched := make([]bool, len(before))
+	added = make(models.Rows, 0)
+	changed = make([]RowChange, 0)
+	for _, row := range after {
+		identity := rowIdentity(row, key)
+		candidates := unmatched[identity]
+		if len(candidates) == 0 {
+			added = append(added, row)
+			continue
+		}
+		unmatched[identity] = candidates[1:]
+		matched[candidates[0]] = true
+		if previous := before[candidates[0]]; len(key) > 0 && rowIdentity(previous, nil) != rowIdentity(row, nil) {
+			changed = append(changed, RowChange{Before: previous, After: row})
+		}
+	}
+
+	removed = make(models.Rows, 0)
+	for i, row := range before {
+		if !matched[i] {
+			removed = append(removed, row)
+		}
+	}
+	return removed, added, changed
+}
+
+// ChangedRows returns a row for every change holding its new values, with each column that
+// changed shown as "old -> new"
+func ChangedRows(changes []RowChange) models.Rows {
+	rows := make(models.Rows, 0, len(changes))
+	for _, change := range changes {
+		row := make(map[string]string, len(change.After))
+		for name, value := range change.Before {
+			if _, ok := change.After[name]; !ok {
+				row[name] = value + " -> "
+			}
+		}
+		for name, value := range change.After {
+			row[name] = value
+			if previous, ok := change.Before[name]; ok && previous != value {
+				row[name] = previous + " -> " + value
+			} else if !ok {
+				row[name] = " -> " + value
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows
+}
+
+// TagRows returns a copy of rows with column set to value in every row
+func TagRows(rows models.Rows, column, value string) models.Rows {
+	tagged := make(models.Rows, 0, len(rows))
+	for _, row := range rows {
+		taggedRow := make(map[string]string, len(row)+1)
+		for name, rowValue := range row {
+			taggedRow[name] = rowValue
+		}
+		taggedRow[column] = value
+		tagged = append(tagged, taggedRow)
+	}
+	return tagged
+}
+
+func rowIdentity(row map[string]string, key []string) string {
+	if len(key) > 0 {
+		keyed := make(map[string]string, len(key))
+		for _, column := range key {
+			keyed[column] = row[column]
+		}
+		row = keyed
+	}
+	// Maps are encoded with sorted keys so equal rows always match
+	identity, _ := json.Marshal(row)
+	return string(identity)
+}
@@ -0,0 +1,42 @@
+package utils
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestDiffRowsWithoutKey(t *testing.T) {
+	before := models.Rows{{"pid": "1", "rss": "10"}, {"pid": "2", "rss": "20"}, {"pid": "2", "rss": "20"}}
+	after := models.Rows{{"pid": "1", "rss": "11"}, {"pid": "2", "rss": "20"}}
+	removed, added, changed := DiffRows(before, after, nil)
+	if !reflect.DeepEqual(removed, models.Rows{{"pid": "1", "rss": "10"}, {"pid": "2", "rss": "20"}}) {
+		t.Errorf("removed = %v", removed)
+	}
+	if !reflect.DeepEqual(added, models.Rows{{"pid": "1", "rss": "11"}}) {
+		t.Errorf("added = %v", added)
+	}
+	if len(changed) != 0 {
+		t.Errorf("changed = %v, rows without a key can't change", changed)
+	}
+}
+
+func TestDiffRowsWithKeyReportsChanges(t *testing.T) {
+	before := models.Rows{{"pid": "1", "rss": "10"}, {"pid": "2", "rss": "20"}, {"pid": "3", "rss": "30"}}
+	after := models.Rows{{"pid": "1", "rss": "10"}, {"pid": "2", "rss": "25"}, {"pid": "4", "rss": "40"}}
+	removed, added, changed := DiffRows(before, after, []string{"pid"})
+	if !reflect.DeepEqual(removed, models.Rows{{"pid": "3", "rss": "30"}}) {
+		t.Errorf("removed = %v", removed)
+	}
+	if !reflect.DeepEqual(added, models.Rows{{"pid": "4", "rss": "40"}}) {
+		t.Errorf("added = %v", added)
+	}
+	want := []RowChange{{Before: map[string]string{"pid": "2", "rss": "20"}, After: map[string]string{"pid": "2", "rss": "25"}}}
+	if !reflect.DeepEqual(changed, want) {
+		t.Fatalf("changed = %v, want %v", changed, want)
+	}
+	if rows := ChangedRows(changed); !reflect.DeepEqual(rows, models.Rows{{"pid": "2", "rss": "20 -> 25"}}) {
+		t.Errorf("ChangedRows() = %v", rows)
+	}
+}
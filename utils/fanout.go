@@ -0,0 +1,82 @@
+package utils
+
+import (
+	"fmt"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// HostResult is the outcome of running a single query on one of many hosts
+type HostResult struct {
+	Host      hosts.Host
+	QueryName string
+	Rows      models.Rows
+	Status    models.QueryStatus
+	Err       error
+}
+
+// MergeHostResults flattens the rows returned by every host into a single row set,
+// tagging each row with the host_uuid and computer_name it came from. If a query returns
+// a column of the same name itself its value is kept, and the host's goes in a column
+// prefixed with underscores until the name is free.
+func MergeHostResults(results []HostResult) models.Rows {
+	columns := make(map[string]bool)
+	for _, result := range results {
+		for _, row := range result.Rows {
+			for column := range row {
+				columns[column] = true
+			}
+		}
+	}
+	uuidColumn := freeColumn(columns, "host_uuid")
+	nameColumn := freeColumn(columns, "computer_name")
+
+	merged := make(models.Rows, 0)
+	for _, result := range results {
+		for _, row := range result.Rows {
+			taggedRow := make(map[string]string, len(row)+2)
+			for column, value := range row {
+				taggedRow[column] = value
+			}
+			taggedRow[uuidColumn] = result.Host.UUID
+			taggedRow[nameColumn] = result.Host.ComputerName
+			merged = append(merged, taggedRow)
+		}
+	}
+	return merged
+}
+
+// freeColumn returns name, prefixed with underscores if needed, so it isn't in columns
+func freeColumn(columns map[string]bool, name string) string {
+	for columns[name] {
+		name = "_" + name
+	}
+	columns[name] = true
+	return name
+}
+
+// HostResultSummary builds a per host status row set for a fanned out query
+func HostResultSummary(results []HostResult) models.Rows {
+	summary := make(models.Rows, 0)
+	for _, result := range results {
+		errorText := ""
+		status := result.Status.State
+		if result.Err != nil {
+			errorText = result.Err.Error()
+			// Keep Failed or Expired reported by the host, anything else failed locally
+			if result.Status.Err() == nil {
+				status = models.QueryFailed
+			}
+		}
+		summary = append(summary, map[string]string{
+			"host_uuid":     result.Host.UUID,
+			"computer_name": result.Host.ComputerName,
+			"query_name":    result.QueryName,
+			"status":        string(status),
+			"rows":          fmt.Sprintf("%d", len(result.Rows)),
+			"error":         errorText,
+		})
+	}
+	return summary
+}
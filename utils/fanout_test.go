@@ -0,0 +1,57 @@
+package utils
+
+import (
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/hosts"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestMergeHostResultsTagsRows(t *testing.T) {
+	merged := MergeHostResults([]HostResult{
+		{Host: hosts.Host{UUID: "a", ComputerName: "alpha"}, Rows: models.Rows{{"pid": "1"}}},
+		{Host: hosts.Host{UUID: "b", ComputerName: "beta"}, Rows: models.Rows{{"pid": "2"}}},
+	})
+	if len(merged) != 2 {
+		t.Fatalf("expected 2 rows, got %d", len(merged))
+	}
+	if merged[1]["host_uuid"] != "b" || merged[1]["computer_name"] != "beta" || merged[1]["pid"] != "2" {
+		t.Fatalf("unexpected row: %v", merged[1])
+	}
+}
+
+func TestMergeHostResultsKeepsQueryColumns(t *testing.T) {
+	merged := MergeHostResults([]HostResult{
+		{Host: hosts.Host{UUID: "a", ComputerName: "alpha"}, Rows: models.Rows{{"computer_name": "from-query"}}},
+		{Host: hosts.Host{UUID: "b", ComputerName: "beta"}, Rows: models.Rows{{"other": "x"}}},
+	})
+	if merged[0]["computer_name"] != "from-query" {
+		t.Fatalf("query value was overwritten: %v", merged[0])
+	}
+	if merged[0]["_computer_name"] != "alpha" || merged[1]["_computer_name"] != "beta" {
+		t.Fatalf("host name not tagged under _computer_name: %v", merged)
+	}
+	if _, ok := merged[1]["computer_name"]; ok {
+		t.Fatalf("rows without the column should not gain it: %v", merged[1])
+	}
+	if merged[1]["host_uuid"] != "b" {
+		t.Fatalf("host_uuid missing: %v", merged[1])
+	}
+}
+
+func TestHostResultSummaryStatus(t *testing.T) {
+	summary := HostResultSummary([]HostResult{
+		{Host: hosts.Host{UUID: "a"}, Status: models.QueryStatus{State: models.QueryComplete}, Rows: models.Rows{{}, {}}},
+		{Host: hosts.Host{UUID: "b"}, Status: models.QueryStatus{State: models.QueryExpired}, Err: models.ErrQueryExpired},
+		{Host: hosts.Host{UUID: "c"}, Err: models.ErrHostNotFound},
+	})
+	want := []string{"Complete", "Expired", "Failed"}
+	for i, row := range summary {
+		if row["status"] != want[i] {
+			t.Errorf("%s: status %s, want %s", row["host_uuid"], row["status"], want[i])
+		}
+	}
+	if summary[0]["rows"] != "2" || summary[2]["error"] != models.ErrHostNotFound.Error() {
+		t.Fatalf("unexpected summary: %v", summary)
+	}
+}
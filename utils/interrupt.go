@@ -0,0 +1,26 @@
+package utils
+
+import (
+	"context"
+	"os"
+	"os/signal"
+)
+
+// InterruptContext returns a copy of parent that is cancelled the first time ctrl C is
+// pressed. The returned cancel function must be called to stop listening for the signal.
+func InterruptContext(parent context.Context) (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithCancel(parent)
+	ctrlcChannel := make(chan os.Signal, 1)
+	signal.Notify(ctrlcChannel, os.Interrupt)
+
+	go func() {
+		select {
+		case <-ctrlcChannel:
+			cancel()
+		case <-ctx.Done():
+		}
+		signal.Stop(ctrlcChannel)
+	}()
+
+	return ctx, cancel
+}
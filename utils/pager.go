@@ -0,0 +1,101 @@
+package utils
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/crypto/ssh/terminal"
+)
+
+// ErrPagerQuit is returned by a Pager once the user has asked it to stop the output
+var ErrPagerQuit = errors.New("Output stopped")
+
+// Pager is an io.Writer that pauses every time a screen of lines has been written until
+// enter is pressed on in. Entering q stops the output and every later write returns
+// ErrPagerQuit, so whatever is printing knows to stop as well.
+type Pager struct {
+	w      io.Writer
+	in     io.Reader
+	height int
+	lines  int
+	quit   bool
+}
+
+// NewPager creates a Pager that writes to w and pauses after every height lines
+func NewPager(w io.Writer, in io.Reader, height int) *Pager {
+	return &Pager{
+		w:      w,
+		in:     in,
+		height: height,
+	}
+}
+
+func (pager *Pager) Write(p []byte) (int, error) {
+	if pager.quit {
+		return 0, ErrPagerQuit
+	}
+	written := 0
+	for len(p) > 0 {
+		end := bytes.IndexByte(p, '\n')
+		if end < 0 {
+			n, err := pager.w.Write(p)
+			return written + n, err
+		}
+		n, err := pager.w.Write(p[:end+1])
+		written += n
+		if err != nil {
+			return written, err
+		}
+		p = p[end+1:]
+
+		pager.lines++
+		if pager.lines < pager.height {
+			continue
+		}
+		pager.lines = 0
+		if !pager.more() {
+			pager.quit = true
+			return written, ErrPagerQuit
+		}
+	}
+	return written, nil
+}
+
+// more asks whether to show the next screen, returning false if the user entered q
+func (pager *Pager) more() bool {
+	fmt.Fprintf(pager.w, "-- More -- (enter for the next page, q to stop) ")
+	// Read a byte at a time so no input meant for the prompt is consumed
+	line := make([]byte, 0)
+	buf := make([]byte, 1)
+	for {
+		n, err := pager.in.Read(buf)
+		if err != nil {
+			return false
+		}
+		if n == 0 {
+			continue
+		}
+		if buf[0] == '\n' {
+			break
+		}
+		line = append(line, buf[0])
+	}
+	return strings.ToLower(strings.TrimSpace(string(line))) != "q"
+}
+
+// TerminalHeight returns the number of lines in the terminal when both stdin and stdout
+// are attached to one, otherwise ok is false
+func TerminalHeight() (height int, ok bool) {
+	if !terminal.IsTerminal(int(os.Stdin.Fd())) || !terminal.IsTerminal(int(os.Stdout.Fd())) {
+		return 0, false
+	}
+	_, height, err := terminal.GetSize(int(os.Stdout.Fd()))
+	if err != nil || height < 2 {
+		return 0, false
+	}
+	return height, true
+}
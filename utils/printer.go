@@ -2,74 +2,151 @@
 package utils
 
 import (
+	"encoding/csv"
 	"encoding/json"
 	"fmt"
+	"io"
 	"sort"
 	"strings"
 
 	"github.com/AbGuthrie/goquery/v2/models"
 )
 
-func prettyPrintQueryResultsJSON(results models.Rows) {
+func prettyPrintQueryResultsJSON(w io.Writer, results models.Rows) error {
 	formatted, err := json.MarshalIndent(results, "", "    ")
 	if err != nil {
-		fmt.Printf("Could not format query results.\n")
-		return
+		return fmt.Errorf("Could not format query results")
 	}
-	fmt.Printf("%s\n", formatted)
+	_, err = fmt.Fprintf(w, "%s\n", formatted)
+	return err
 }
 
-func prettyPrintQueryResultsLines(results models.Rows) {
+func prettyPrintQueryResultsLines(w io.Writer, results models.Rows) error {
 	if len(results) == 0 {
-		return
+		return nil
 	}
 	// To center align keys with "=" get longest length key name
+	sortedKeys := allColumnKeys(results)
 	keyPadding := 0
-	for key := range results[0] {
+	for _, key := range sortedKeys {
 		if len(key) > keyPadding {
 			keyPadding = len(key)
 		}
 	}
-	sortedKeys := sortedColumnKeys(results[0])
 	for _, row := range results {
 		for _, key := range sortedKeys {
-			fmt.Printf("%*s = %s\n", keyPadding, key, row[key])
+			fmt.Fprintf(w, "%*s = %s\n", keyPadding, key, row[key])
+		}
+		if _, err := fmt.Fprintf(w, "\n"); err != nil {
+			return err
 		}
-		fmt.Printf("\n")
 	}
+	return nil
 }
 
-func prettyPrintQueryResultsPretty(results models.Rows) {
+func prettyPrintQueryResultsPretty(w io.Writer, results models.Rows) error {
 	maxLens, err := calculateMaxColumnLengths(results)
 	if err != nil {
-		return
+		return nil
 	}
 
-	keyOrder := sortedColumnKeys(results[0])
+	keyOrder := allColumnKeys(results)
+	divider := prettyDivider(maxLens)
+
+	prettyPrintHeader(w, keyOrder, maxLens, divider)
+	prettyPrintRows(w, results, keyOrder, maxLens)
 
+	_, err = fmt.Fprintf(w, "%s\n", divider)
+	return err
+}
+
+func prettyDivider(maxLens map[string]int) string {
 	dividerLength := 0
 	for _, padding := range maxLens {
 		dividerLength += padding
 	}
 	// Then max length of all keys + divider and space (| %s ) + the final divider
-	divider := strings.Repeat("-", dividerLength+len(maxLens)*3+1)
+	return strings.Repeat("-", dividerLength+len(maxLens)*3+1)
+}
 
-	// Print header
-	fmt.Printf("%s\n", divider)
+func prettyPrintHeader(w io.Writer, keyOrder []string, maxLens map[string]int, divider string) {
+	fmt.Fprintf(w, "%s\n", divider)
 	for _, columnName := range keyOrder {
-		fmt.Printf("| %-*s ", maxLens[columnName], columnName)
+		fmt.Fprintf(w, "| %-*s ", maxLens[columnName], columnName)
 	}
-	fmt.Printf("|\n%s\n", divider)
+	fmt.Fprintf(w, "|\n%s\n", divider)
+}
 
-	// Print results
+func prettyPrintRows(w io.Writer, results models.Rows, keyOrder []string, maxLens map[string]int) error {
 	for _, row := range results {
 		for _, columnName := range keyOrder {
-			fmt.Printf("| %-*s ", maxLens[columnName], row[columnName])
+			fmt.Fprintf(w, "| %-*s ", maxLens[columnName], row[columnName])
+		}
+		if _, err := fmt.Fprintf(w, "|\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func prettyPrintQueryResultsDelimited(w io.Writer, results models.Rows, delimiter rune) error {
+	if len(results) == 0 {
+		return nil
+	}
+	keyOrder := allColumnKeys(results)
+	if err := writeDelimitedRecord(w, keyOrder, delimiter); err != nil {
+		return err
+	}
+	return writeDelimitedRows(w, results, keyOrder, delimiter)
+}
+
+func writeDelimitedRows(w io.Writer, results models.Rows, keyOrder []string, delimiter rune) error {
+	for _, row := range results {
+		values := make([]string, len(keyOrder))
+		for i, columnName := range keyOrder {
+			values[i] = row[columnName]
+		}
+		if err := writeDelimitedRecord(w, values, delimiter); err != nil {
+			return err
 		}
-		fmt.Printf("|\n")
 	}
+	return nil
+}
+
+// tsvEscaper escapes the characters that would break a TSV record, TSV has no quoting rules
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n", "\r", "\\r")
 
-	fmt.Printf("%s\n", divider)
+func writeDelimitedRecord(w io.Writer, values []string, delimiter rune) error {
+	if delimiter == '\t' {
+		escaped := make([]string, len(values))
+		for i, value := range values {
+			escaped[i] = tsvEscaper.Replace(value)
+		}
+		_, err := fmt.Fprintf(w, "%s\n", strings.Join(escaped, "\t"))
+		return err
+	}
+
+	csvWriter := csv.NewWriter(w)
+	csvWriter.Comma = delimiter
+	if err := csvWriter.Write(values); err != nil {
+		return err
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+func prettyPrintQueryResultsNDJSON(w io.Writer, results models.Rows) error {
+	for _, row := range results {
+		// encoding/json sorts map keys so column order is deterministic
+		formatted, err := json.Marshal(row)
+		if err != nil {
+			return fmt.Errorf("Could not format query results")
+		}
+		if _, err := fmt.Fprintf(w, "%s\n", formatted); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func calculateMaxColumnLengths(results models.Rows) (map[string]int, error) {
@@ -80,7 +157,7 @@ func calculateMaxColumnLengths(results models.Rows) (map[string]int, error) {
 	maxLengths := make(map[string]int)
 
 	// They key may be longer than the values in some cases like inode
-	for columnName := range results[0] {
+	for _, columnName := range allColumnKeys(results) {
 		maxLengths[columnName] = len(columnName)
 	}
 
@@ -97,10 +174,19 @@ func calculateMaxColumnLengths(results models.Rows) (map[string]int, error) {
 	return maxLengths, nil
 }
 
-func sortedColumnKeys(results map[string]string) []string {
+// allColumnKeys returns the sorted union of column names across every row, rows from
+// different hosts or tables are not guaranteed to share the same columns
+func allColumnKeys(results models.Rows) []string {
+	seen := make(map[string]bool)
 	keys := make([]string, 0)
-	for key := range results {
-		keys = append(keys, key)
+	for _, row := range results {
+		for key := range row {
+			if seen[key] {
+				continue
+			}
+			seen[key] = true
+			keys = append(keys, key)
+		}
 	}
 	sort.Strings(keys)
 	return keys
@@ -0,0 +1,64 @@
+package utils
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/config"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestFprintQueryResults(t *testing.T) {
+	tests := []struct {
+		name      string
+		printMode config.PrintModeEnum
+		rows      models.Rows
+		want      string
+	}{
+		{
+			"csv quotes delimiters, quotes and newlines",
+			config.PrintCSV,
+			models.Rows{{"a": "x,y", "b": `say "hi"`, "c": "two\nlines"}},
+			"a,b,c\n\"x,y\",\"say \"\"hi\"\"\",\"two\nlines\"\n",
+		},
+		{
+			"tsv escapes tabs, newlines and backslashes",
+			config.PrintTSV,
+			models.Rows{{"a": "x\ty", "b": "two\r\nlines", "c": `C:\dir`}},
+			"a\tb\tc\nx\\ty\ttwo\\r\\nlines\tC:\\\\dir\n",
+		},
+		{
+			"csv takes columns from every row",
+			config.PrintCSV,
+			models.Rows{{"pid": "1"}, {"pid": "2", "name": "launchd"}},
+			"name,pid\n,1\nlaunchd,2\n",
+		},
+		{
+			"line mode takes columns from every row",
+			config.PrintLine,
+			models.Rows{{"pid": "1"}, {"pid": "2", "name": "launchd"}},
+			"name = \n pid = 1\n\nname = launchd\n pid = 2\n\n",
+		},
+		{
+			"pretty mode takes columns from every row",
+			config.PrintPretty,
+			models.Rows{{"pid": "1"}, {"pid": "2", "name": "launchd"}},
+			"-----------------\n| name    | pid |\n-----------------\n|         | 1   |\n| launchd | 2   |\n-----------------\n",
+		},
+		{
+			"ndjson keeps each row's own columns",
+			config.PrintNDJSON,
+			models.Rows{{"pid": "1"}, {"pid": "2", "name": "launchd"}},
+			"{\"pid\":\"1\"}\n{\"name\":\"launchd\",\"pid\":\"2\"}\n",
+		},
+	}
+	for _, test := range tests {
+		out := &bytes.Buffer{}
+		if err := FprintQueryResults(out, test.rows, test.printMode); err != nil {
+			t.Fatalf("%s: %s", test.name, err)
+		}
+		if out.String() != test.want {
+			t.Errorf("%s: got\n%q\nwant\n%q", test.name, out.String(), test.want)
+		}
+	}
+}
@@ -0,0 +1,162 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/AbGuthrie/goquery/v2/config"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+// RowPrinter prints a result set in a print mode a page at a time, so large result sets
+// can be written as they are fetched instead of all at once. Close must be called after
+// the last page to finish the output.
+//
+// Columns first seen on a later page are added as they appear. The pretty and delimited
+// modes print their header again when that happens, and pretty mode also widens columns
+// for longer values.
+type RowPrinter struct {
+	w         io.Writer
+	printMode config.PrintModeEnum
+	keyOrder  []string
+	maxLens   map[string]int
+	rows      int
+}
+
+// NewRowPrinter creates a RowPrinter that writes to w formatted with printMode
+func NewRowPrinter(w io.Writer, printMode config.PrintModeEnum) *RowPrinter {
+	return &RowPrinter{
+		w:         w,
+		printMode: printMode,
+	}
+}
+
+// Print writes the next page of rows
+func (printer *RowPrinter) Print(results models.Rows) error {
+	if len(results) == 0 {
+		return nil
+	}
+	var err error
+	switch printer.printMode {
+	case config.PrintJSON:
+		err = printer.printJSON(results)
+	case config.PrintLine:
+		err = prettyPrintQueryResultsLines(printer.w, results)
+	case config.PrintCSV:
+		err = printer.printDelimited(results, ',')
+	case config.PrintTSV:
+		err = printer.printDelimited(results, '\t')
+	case config.PrintNDJSON:
+		err = prettyPrintQueryResultsNDJSON(printer.w, results)
+	default:
+		err = printer.printPretty(results)
+	}
+	printer.rows += len(results)
+	return err
+}
+
+// Close finishes the output once every page has been printed
+func (printer *RowPrinter) Close() error {
+	switch printer.printMode {
+	case config.PrintJSON:
+		if printer.rows == 0 {
+			_, err := fmt.Fprintf(printer.w, "[]\n")
+			return err
+		}
+		_, err := fmt.Fprintf(printer.w, "\n]\n")
+		return err
+	case config.PrintLine, config.PrintCSV, config.PrintTSV, config.PrintNDJSON:
+		return nil
+	}
+	if printer.rows == 0 {
+		return nil
+	}
+	_, err := fmt.Fprintf(printer.w, "%s\n", prettyDivider(printer.maxLens))
+	return err
+}
+
+// Rows is the number of rows printed so far
+func (printer *RowPrinter) Rows() int {
+	return printer.rows
+}
+
+// printJSON writes rows as elements of a single array, matching the output of
+// prettyPrintQueryResultsJSON for the whole result set
+func (printer *RowPrinter) printJSON(results models.Rows) error {
+	for i, row := range results {
+		separator := ",\n"
+		if printer.rows == 0 && i == 0 {
+			separator = "[\n"
+		}
+		formatted, err := json.MarshalIndent(row, "    ", "    ")
+		if err != nil {
+			return fmt.Errorf("Could not format query results")
+		}
+		if _, err := fmt.Fprintf(printer.w, "%s    %s", separator, formatted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (printer *RowPrinter) printDelimited(results models.Rows, delimiter rune) error {
+	if printer.addColumns(results) {
+		if err := writeDelimitedRecord(printer.w, printer.keyOrder, delimiter); err != nil {
+			return err
+		}
+	}
+	return writeDelimitedRows(printer.w, results, printer.keyOrder, delimiter)
+}
+
+// addColumns adds the columns of results not printed before to keyOrder, keeping it
+// sorted, and reports whether there were any
+func (printer *RowPrinter) addColumns(results models.Rows) bool {
+	known := make(map[string]bool, len(printer.keyOrder))
+	for _, columnName := range printer.keyOrder {
+		known[columnName] = true
+	}
+	added := false
+	for _, columnName := range allColumnKeys(results) {
+		if !known[columnName] {
+			printer.keyOrder = append(printer.keyOrder, columnName)
+			added = true
+		}
+	}
+	if added {
+		sort.Strings(printer.keyOrder)
+	}
+	return added
+}
+
+func (printer *RowPrinter) printPretty(results models.Rows) error {
+	pageLens, err := calculateMaxColumnLengths(results)
+	if err != nil {
+		return nil
+	}
+	if printer.maxLens == nil {
+		printer.maxLens = make(map[string]int)
+	}
+
+	changed := false
+	for _, columnName := range allColumnKeys(results) {
+		length := pageLens[columnName]
+		if len(columnName) > length {
+			length = len(columnName)
+		}
+		current, known := printer.maxLens[columnName]
+		if !known {
+			printer.keyOrder = append(printer.keyOrder, columnName)
+		}
+		if !known || length > current {
+			printer.maxLens[columnName] = length
+			changed = true
+		}
+	}
+	if changed {
+		sort.Strings(printer.keyOrder)
+		prettyPrintHeader(printer.w, printer.keyOrder, printer.maxLens, prettyDivider(printer.maxLens))
+	}
+	return prettyPrintRows(printer.w, results, printer.keyOrder, printer.maxLens)
+}
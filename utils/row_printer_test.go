@@ -0,0 +1,47 @@
+package utils
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/AbGuthrie/goquery/v2/config"
+	"github.com/AbGuthrie/goquery/v2/models"
+)
+
+func TestRowPrinterPrettyAddsLaterColumns(t *testing.T) {
+	out := &bytes.Buffer{}
+	printer := NewRowPrinter(out, config.PrintPretty)
+	printer.Print(models.Rows{{"pid": "1"}})
+	printer.Print(models.Rows{{"pid": "2", "name": "launchd"}})
+	printer.Close()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	want := []string{
+		"-------",
+		"| pid |",
+		"-------",
+		"| 1   |",
+		"-----------------",
+		"| name    | pid |",
+		"-----------------",
+		"| launchd | 2   |",
+		"-----------------",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Fatalf("unexpected output:\n%s", out)
+	}
+}
+
+func TestRowPrinterDelimitedAddsLaterColumns(t *testing.T) {
+	out := &bytes.Buffer{}
+	printer := NewRowPrinter(out, config.PrintCSV)
+	printer.Print(models.Rows{{"pid": "1"}})
+	printer.Print(models.Rows{{"pid": "2"}})
+	printer.Print(models.Rows{{"pid": "3", "name": "launchd"}})
+	printer.Close()
+
+	if want := "pid\n1\n2\nname,pid\nlaunchd,3\n"; out.String() != want {
+		t.Fatalf("got %q, want %q", out.String(), want)
+	}
+}
@@ -2,19 +2,26 @@
 package utils
 
 import (
+	"io"
+
 	"github.com/AbGuthrie/goquery/v2/config"
 	"github.com/AbGuthrie/goquery/v2/models"
 )
 
-// PrettyPrintQueryResults prints a given []result map set to standard out
-// taking into consideration the current state.go's print mode
-func PrettyPrintQueryResults(results models.Rows, printMode config.PrintModeEnum) {
+// FprintQueryResults writes a given []result map set to w formatted with printMode
+func FprintQueryResults(w io.Writer, results models.Rows, printMode config.PrintModeEnum) error {
 	switch printMode {
 	case config.PrintJSON:
-		prettyPrintQueryResultsJSON(results)
+		return prettyPrintQueryResultsJSON(w, results)
 	case config.PrintLine:
-		prettyPrintQueryResultsLines(results)
+		return prettyPrintQueryResultsLines(w, results)
+	case config.PrintCSV:
+		return prettyPrintQueryResultsDelimited(w, results, ',')
+	case config.PrintTSV:
+		return prettyPrintQueryResultsDelimited(w, results, '\t')
+	case config.PrintNDJSON:
+		return prettyPrintQueryResultsNDJSON(w, results)
 	default:
-		prettyPrintQueryResultsPretty(results)
+		return prettyPrintQueryResultsPretty(w, results)
 	}
 }